@@ -5,25 +5,21 @@ import (
 	"os"
 
 	"github.com/cloudinary/cloudinary-go/v2"
-	"github.com/joho/godotenv"
 )
 
 var CLD *cloudinary.Cloudinary
 
+// InitCloudinary connects to Cloudinary when its credentials are set. Without
+// them CLD stays nil and files are stored elsewhere (see utils.InitStorage).
 func InitCloudinary() {
 
-	err := godotenv.Load()
-
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
 	cloudName := os.Getenv("CLOUDINARY_CLOUD_NAME")
 	apiKey := os.Getenv("CLOUDINARY_API_KEY")
 	apiSecret := os.Getenv("CLOUDINARY_API_SECRET")
 
 	if cloudName == "" || apiKey == "" || apiSecret == "" {
-		log.Fatal("Missing Cloudinary environment variables")
+		log.Println("⚠️ Cloudinary environment variables are not set, skipping Cloudinary")
+		return
 	}
 
 	cld, err := cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// currentUserID returns the authenticated user's ID from the JWT context
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// isModerator reports whether the authenticated user holds a moderation role
+// and logged in with two-factor authentication
+func isModerator(c *gin.Context) bool {
+	return models.IsModeratorRole(c.GetString("userRole")) && c.GetBool("mfa")
+}
+
+// isOwner reports whether the authenticated user created the resource
+func isOwner(c *gin.Context, ownerID primitive.ObjectID) bool {
+	userID, ok := currentUserID(c)
+	return ok && !ownerID.IsZero() && userID == ownerID
+}
+
+// canModify reports whether the authenticated user owns the resource or is a moderator
+func canModify(c *gin.Context, ownerID primitive.ObjectID) bool {
+	if isModerator(c) {
+		return true
+	}
+
+	return isOwner(c, ownerID)
+}
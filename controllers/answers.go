@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// answerPreviewSize is how many top answers the question feed includes per question
+const answerPreviewSize = 3
+
+var (
+	answerCollection *mongo.Collection
+	answerOnce       sync.Once
+)
+
+func getAnswerCollection() *mongo.Collection {
+	answerOnce.Do(func() {
+		answerCollection = config.GetCollection("answers")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = answerCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "questionId", Value: 1}, {Key: "upvotes", Value: -1}, {Key: "createdAt", Value: 1}}},
+			{Keys: bson.D{{Key: "questionId", Value: 1}, {Key: "createdAt", Value: 1}}},
+		})
+	})
+	return answerCollection
+}
+
+// answerSorts maps each ?sort= mode of the answers endpoint to its order
+var answerSorts = map[string]bson.D{
+	"top":    {{Key: "upvotes", Value: -1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+	"oldest": {{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+	"newest": {{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+}
+
+// fillAnswerPreviews sets Answers on each question to its top few visible answers
+func fillAnswerPreviews(ctx context.Context, questions []models.Qna) error {
+	if len(questions) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(questions))
+	for i, q := range questions {
+		ids[i] = q.ID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"questionId": bson.M{"$in": ids}, "moderationStatus": bson.M{"$exists": false}}}},
+		{{Key: "$sort", Value: answerSorts["top"]}},
+		{{Key: "$group", Value: bson.M{"_id": "$questionId", "answers": bson.M{"$push": "$$ROOT"}}}},
+		{{Key: "$project", Value: bson.M{"answers": bson.M{"$slice": bson.A{"$answers", answerPreviewSize}}}}},
+	}
+
+	cursor, err := getAnswerCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	var groups []struct {
+		QuestionID primitive.ObjectID `bson:"_id"`
+		Answers    []models.Answer    `bson:"answers"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return err
+	}
+
+	previews := map[primitive.ObjectID][]models.Answer{}
+	for _, g := range groups {
+		previews[g.QuestionID] = g.Answers
+	}
+	for i := range questions {
+		questions[i].Answers = previews[questions[i].ID]
+		if questions[i].Answers == nil {
+			questions[i].Answers = []models.Answer{}
+		}
+	}
+	return nil
+}
+
+// GetAnswers lists a question's answers a page at a time, ?sort=top|oldest|newest
+func GetAnswers(c *gin.Context) {
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	sort, ok := answerSorts[c.DefaultQuery("sort", "top")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use top, oldest or newest"})
+		return
+	}
+
+	page, limit := pageParams(c, 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var question models.Qna
+	if err := getQnaCollection().FindOne(ctx, bson.M{"_id": qnaID}).Decode(&question); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	// Duplicates and their original share their answers
+	group, err := duplicateGroup(ctx, &question)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+
+	filter := bson.M{"questionId": bson.M{"$in": group}}
+	if !isModerator(c) {
+		filter["moderationStatus"] = bson.M{"$exists": false}
+	}
+
+	answers := getAnswerCollection()
+	total, err := answers.CountDocuments(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+
+	opts := options.Find().SetSort(sort).SetSkip(int64((page - 1) * limit)).SetLimit(int64(limit))
+	cursor, err := answers.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+
+	result := []models.Answer{}
+	if err := cursor.All(ctx, &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding data"})
+		return
+	}
+
+	if userID, ok := currentUserID(c); ok {
+		ids := make([]primitive.ObjectID, len(result))
+		for i, a := range result {
+			ids[i] = a.ID
+		}
+		votes, err := userVotes(ctx, userID, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch votes"})
+			return
+		}
+		for i := range result {
+			result[i].MyVote = votes[result[i].ID]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"answers": result, "pagination": commentPagination(page, limit, total)})
+}
+
+// MigrateEmbeddedAnswers moves answers still embedded in qna documents into the
+// answers collection and sets each question's answerCount. Answer IDs are kept,
+// so votes and comments stay attached. It is safe to run more than once.
+func MigrateEmbeddedAnswers(ctx context.Context) (int, error) {
+	questions := getQnaCollection()
+	answers := getAnswerCollection()
+
+	cursor, err := questions.Find(ctx, bson.M{"answers": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var legacy struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			Answers []models.Answer    `bson:"answers"`
+		}
+		if err := cursor.Decode(&legacy); err != nil {
+			return migrated, err
+		}
+
+		for _, answer := range legacy.Answers {
+			if answer.ID.IsZero() {
+				answer.ID = primitive.NewObjectID()
+			}
+			answer.QuestionID = legacy.ID
+			if _, err := answers.InsertOne(ctx, answer); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return migrated, err
+			}
+			migrated++
+		}
+
+		count, err := answers.CountDocuments(ctx, bson.M{"questionId": legacy.ID})
+		if err != nil {
+			return migrated, err
+		}
+
+		_, err = questions.UpdateOne(ctx, bson.M{"_id": legacy.ID}, bson.M{
+			"$set":   bson.M{"answerCount": count},
+			"$unset": bson.M{"answers": ""},
+		})
+		if err != nil {
+			return migrated, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return migrated, err
+	}
+
+	log.Printf("✅ Moved %d embedded answers into the answers collection", migrated)
+	return migrated, nil
+}
+
+// answerAuthors maps each of the question's given answers to its author
+func answerAuthors(ctx context.Context, questionID primitive.ObjectID, answerIDs []primitive.ObjectID) (map[primitive.ObjectID]primitive.ObjectID, error) {
+	authors := map[primitive.ObjectID]primitive.ObjectID{}
+	if len(answerIDs) == 0 {
+		return authors, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"postedById": 1})
+	cursor, err := getAnswerCollection().Find(ctx, bson.M{"_id": bson.M{"$in": answerIDs}, "questionId": questionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var answers []models.Answer
+	if err := cursor.All(ctx, &answers); err != nil {
+		return nil, err
+	}
+	for _, a := range answers {
+		authors[a.ID] = a.PostedByID
+	}
+	return authors, nil
+}
@@ -2,7 +2,13 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,18 +28,70 @@ var (
 	once           sync.Once
 )
 
+// emailCollation compares emails case-insensitively. Lookups by an email the
+// user typed use it so they match the unique index below.
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
 func getUserCollection() *mongo.Collection {
 	once.Do(func() {
 		userCollection = config.GetCollection("users")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, err := userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    primitive.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetCollation(emailCollation),
+		})
+		if err != nil {
+			log.Println("⚠️ Failed to create unique email index:", err)
+		}
+
+		// A provider account can be linked to one user only
+		_, err = userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: primitive.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(primitive.M{"identities.subject": primitive.M{"$exists": true}}),
+		})
+		if err != nil {
+			log.Println("⚠️ Failed to create unique identity index:", err)
+		}
 	})
 	return userCollection
 }
 
+// normalizeEmail is how emails are stored: trimmed and lowercase
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// isValidEmail accepts only a bare address such as "name@example.com"
+func isValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
+
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(hashedPassword), err
 }
 
+// issueTokens opens a new session for the user and returns an access and refresh token pair.
+// mfa records whether the user proved a second factor for this session.
+func issueTokens(c *gin.Context, ctx context.Context, user models.User, mfa bool) (string, string, error) {
+	session, refreshToken, err := utils.CreateSession(ctx, user.ID, c.Request.UserAgent(), c.ClientIP(), mfa)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := utils.GenerateToken(user, session)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
 func Signup(c *gin.Context) {
 
 	userCollection := getUserCollection()
@@ -53,17 +111,23 @@ func Signup(c *gin.Context) {
 		return
 	}
 
+	user.Email = normalizeEmail(user.Email)
+	if !isValidEmail(user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
 	// Check if user exists
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	count, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
+	count, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email}, options.Count().SetCollation(emailCollation))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking user"})
 		return
 	}
 	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already registered"})
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
 		return
 	}
 
@@ -76,23 +140,38 @@ func Signup(c *gin.Context) {
 
 	user.Password = hashedPassword
 	user.ID = primitive.NewObjectID()
+	user.Role = models.RoleStudent // roles are only granted by admins
+	user.EmailVerified = false
+	user.Reputation = 0
+	user.TOTPEnabled = false
+	user.Identities = nil
+	user.Contributions = []primitive.ObjectID{}
 	user.CreatedAt = time.Now()
 
 	// Insert user into database
 	_, err = userCollection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating user", "details": err.Error()})
 		return
 	}
 
+	// The account works right away, but contributing requires a verified email
+	if err := sendVerificationEmail(ctx, user); err != nil {
+		log.Println("⚠️ Failed to send verification email:", err)
+	}
+
 	// Generate JWT Token (Now using utils package)
-	token, err := utils.GenerateToken(user.Email, user.FullName, user.ID.Hex())
+	token, refreshToken, err := issueTokens(c, ctx, user, false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully", "token": token})
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully, please check your email to verify your account", "token": token, "refreshToken": refreshToken})
 }
 
 // Login Controller
@@ -109,31 +188,136 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Find user
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	var user models.User
-	err := userCollection.FindOne(ctx, bson.M{"email": credentials.Email}).Decode(&user)
+
+	// Refuse early while the account or IP is backing off
+	throttle := utils.GetLoginThrottle()
+	wait, err := throttle.Attempt(ctx, credentials.Email, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		log.Println("⚠️ Login throttle check failed:", err)
+	}
+	if wait > 0 {
+		tooManyAttempts(c, wait)
 		return
 	}
 
-	// Compare Passwords
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credentials.Password))
+	// Find user
+	var user models.User
+	err = userCollection.FindOne(ctx, bson.M{"email": strings.TrimSpace(credentials.Email)}, options.FindOne().SetCollation(emailCollation)).Decode(&user)
+	if err == nil {
+		// Compare Passwords
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(credentials.Password))
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
+	if err := throttle.Success(ctx, credentials.Email, c.ClientIP()); err != nil {
+		log.Println("⚠️ Failed to reset login attempts:", err)
+	}
+
+	// With two-factor enabled the password only earns a challenge token
+	if user.TOTPEnabled {
+		challengeToken, err := utils.GenerateChallengeToken(user.ID.Hex())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication required", "twoFactorRequired": true, "challengeToken": challengeToken})
+		return
+	}
+
 	// Generate JWT Token (Now using utils package)
-	token, err := utils.GenerateToken(user.Email, user.FullName, user.ID.Hex())
+	token, refreshToken, err := issueTokens(c, ctx, user, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "token": token, "refreshToken": refreshToken})
+}
+
+// tooManyAttempts answers 429 with a Retry-After header in whole seconds
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "Too many failed login attempts, please try again later",
+		"retryAfter": seconds,
+	})
+}
+
+// RefreshToken rotates a refresh token and issues a new access token
+func RefreshToken(c *gin.Context) {
+
+	userCollection := getUserCollection()
+
+	var request struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := c.BindJSON(&request); err != nil || request.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, refreshToken, err := utils.RotateSession(ctx, request.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	err = userCollection.FindOne(ctx, bson.M{"_id": session.UserID}).Decode(&user)
+	if err != nil {
+		_ = utils.RevokeSession(ctx, session.ID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, err := utils.GenerateToken(user, session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "token": token})
+	c.JSON(http.StatusOK, gin.H{"token": token, "refreshToken": refreshToken})
+}
+
+// Logout revokes the current session, or every session of the user with ?all=true
+func Logout(c *gin.Context) {
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if c.Query("all") == "true" {
+		err = utils.RevokeAllSessions(ctx, userID)
+	} else {
+		err = utils.RevokeSession(ctx, sessionID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
 // GetUserProfile retrieves the authenticated user's profile
@@ -221,14 +405,208 @@ func UpdateUserProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
 
+// ChangeEmail starts moving the authenticated user to a new email address after
+// checking their password. The address is only switched by ConfirmEmailChange once
+// the link sent to it is opened, and the old address is told about the request.
+func ChangeEmail(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewEmail        string `json:"newEmail"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	newEmail := normalizeEmail(request.NewEmail)
+	if !isValidEmail(newEmail) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, ctx, user, request.CurrentPassword) {
+		return
+	}
+
+	if strings.EqualFold(newEmail, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email is the same as the current one"})
+		return
+	}
+
+	// Checked again by the unique index when the change is confirmed
+	count, err := userCollection.CountDocuments(ctx, bson.M{"email": newEmail}, options.Count().SetCollation(emailCollation))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking user"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	// The address only changes once its owner opens the link sent to it
+	_, err = userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"pendingEmail": newEmail, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	if err := sendEmailChangeConfirmation(ctx, user, newEmail); err != nil {
+		log.Println("⚠️ Failed to send email change confirmation:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		return
+	}
+
+	notice := fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address of your PrepPortal account to %s. It changes once the link sent to that address is opened.\n\nIf this wasn't you, reset your password now.", user.FullName, newEmail)
+	if err := utils.SendMail(user.Email, "Your PrepPortal email is being changed", notice); err != nil {
+		log.Println("⚠️ Failed to notify old email address:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Please confirm the change from the link sent to your new address", "pendingEmail": newEmail})
+}
+
+// UpdateUserRole lets an admin grant or revoke moderator/admin roles
+func UpdateUserRole(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := c.BindJSON(&request); err != nil || !models.IsValidRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of student, moderator or admin"})
+		return
+	}
+
+	result, err := userCollection.UpdateOne(context.TODO(),
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"role": request.Role, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+// PromoteToAdmin gives the account with this email the admin role. Admins are
+// otherwise only made by other admins, so this bootstraps the first one.
+func PromoteToAdmin(ctx context.Context, email string) error {
+	result, err := getUserCollection().UpdateOne(ctx,
+		bson.M{"email": strings.TrimSpace(email)},
+		bson.M{"$set": bson.M{"role": models.RoleAdmin, "updatedAt": time.Now()}},
+		options.Update().SetCollation(emailCollation),
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no user with email %q", email)
+	}
+	return nil
+}
+
 //GetLeaderBoard of Contributors
 
+// leaderboardEntry is the public view of a user on the leaderboard
+type leaderboardEntry struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	FullName   string             `bson:"fullName" json:"fullName"`
+	Reputation int                `bson:"reputation" json:"reputation"`
+	Points     int                `bson:"points" json:"points"` // earned within the window
+}
+
+// GetLeaderboard ranks contributors by reputation, either all-time or
+// for ?window=weekly|monthly using the reputation ledger
 func GetLeaderboard(c *gin.Context) {
-	opts := options.Find().SetSort(bson.M{"reputation": -1}).SetLimit(10)
-	cursor, _ := userCollection.Find(context.TODO(), bson.M{}, opts)
+	userCollection := getUserCollection()
+
+	since, ok := leaderboardSince(c.Query("window"), time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Window must be weekly, monthly or all"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entries := []leaderboardEntry{}
+
+	if since.IsZero() {
+		opts := options.Find().
+			SetSort(primitive.D{{Key: "reputation", Value: -1}, {Key: "_id", Value: 1}}).
+			SetLimit(int64(limit)).
+			SetProjection(bson.M{"fullName": 1, "reputation": 1, "points": "$reputation"})
+
+		cursor, err := userCollection.Find(ctx, bson.M{"reputation": bson.M{"$gt": 0}}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+			return
+		}
+		if err := cursor.All(ctx, &entries); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding leaderboard"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entries)
+		return
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"createdAt": bson.M{"$gte": since}}},
+		{"$group": bson.M{"_id": "$userId", "points": bson.M{"$sum": "$points"}}},
+		{"$match": bson.M{"points": bson.M{"$gt": 0}}},
+		{"$sort": primitive.D{{Key: "points", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": limit},
+		{"$lookup": bson.M{"from": "users", "localField": "_id", "foreignField": "_id", "as": "user"}},
+		{"$unwind": "$user"},
+		{"$project": bson.M{"fullName": "$user.fullName", "reputation": "$user.reputation", "points": 1}},
+	}
 
-	var users []models.User
-	cursor.All(context.TODO(), &users)
+	cursor, err := getReputationCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leaderboard"})
+		return
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding leaderboard"})
+		return
+	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, entries)
 }
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"sync"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -23,6 +25,14 @@ var (
 func GetBlogCollection() *mongo.Collection {
 	blogOnce.Do(func() {
 		blogCollection = config.GetCollection("blogs")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = blogCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "imageKey", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		})
 	})
 
 	return blogCollection
@@ -70,21 +80,28 @@ func CreateBlog(c *gin.Context) {
 	// Handle Image Upload
 	file, fileHeader, err := c.Request.FormFile("image")
 	if err == nil {
-		imageURL, err := utils.UploadImage(file, fileHeader)
+		stored, err := utils.UploadImage(file, fileHeader)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
 			return
 		}
-		blog.ImageURL = imageURL
+		blog.ImageURL = stored.URL
+		blog.ImageKey = stored.Key
 	}
 
 	// Insert into DB
 	_, err = blogCollection.InsertOne(context.TODO(), blog)
 	if err != nil {
+		releaseFile(context.TODO(), blog.ImageKey)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving blog"})
 		return
 	}
 
+	err = awardReputation(context.TODO(), user.ID, pointsBlogPublished, models.ReputationBlogPublished, blog.ID, "blog:"+blog.ID.Hex())
+	if err != nil {
+		log.Println("⚠️ Failed to award reputation:", err)
+	}
+
 	// Update user's contributions list
 	_, err = userCollection.UpdateOne(context.TODO(), bson.M{"_id": user.ID}, bson.M{"$push": bson.M{"contributions": blog.ID}})
 	if err != nil {
@@ -99,7 +116,7 @@ func CreateBlog(c *gin.Context) {
 func GetAllBlogs(c *gin.Context) {
 
 	blogCollection := GetBlogCollection()
-	cursor, err := blogCollection.Find(context.TODO(), bson.M{})
+	cursor, err := blogCollection.Find(context.TODO(), bson.M{"moderationStatus": bson.M{"$exists": false}})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching blogs"})
 		return
@@ -132,15 +149,20 @@ func GetBlog(c *gin.Context) {
 		return
 	}
 
+	// Moderated blogs are only visible to their author and moderators
+	if blog.ModerationStatus != "" && !canModify(c, blog.AuthorID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, blog)
 }
 
-// UpdateBlog allows authors to update their blogs
+// UpdateBlog allows authors (or moderators) to update blogs
 func UpdateBlog(c *gin.Context) {
 
 	blogCollection := GetBlogCollection()
-	userEmail, exists := c.Get("userEmail")
-	if !exists {
+	if _, exists := c.Get("userEmail"); !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
@@ -157,7 +179,19 @@ func UpdateBlog(c *gin.Context) {
 		return
 	}
 
-	filter := bson.M{"_id": blogID, "author": userEmail}
+	var existing models.Blog
+	err = blogCollection.FindOne(context.TODO(), bson.M{"_id": blogID}).Decode(&existing)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+		return
+	}
+
+	if !canModify(c, existing.AuthorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own blogs"})
+		return
+	}
+
+	filter := bson.M{"_id": blogID}
 	update := bson.M{"$set": bson.M{"title": blogUpdate.Title, "content": blogUpdate.Content, "updatedAt": time.Now()}}
 
 	result, err := blogCollection.UpdateOne(context.TODO(), filter, update)
@@ -169,12 +203,11 @@ func UpdateBlog(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Blog updated successfully"})
 }
 
-// DeleteBlog allows authors to delete their blogs
+// DeleteBlog allows authors (or moderators) to delete blogs
 func DeleteBlog(c *gin.Context) {
 
 	blogCollection := GetBlogCollection()
-	userEmail, exists := c.Get("userEmail")
-	if !exists {
+	if _, exists := c.Get("userEmail"); !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
@@ -185,12 +218,30 @@ func DeleteBlog(c *gin.Context) {
 		return
 	}
 
-	filter := bson.M{"_id": blogID, "author": userEmail}
+	var existing models.Blog
+	err = blogCollection.FindOne(context.TODO(), bson.M{"_id": blogID}).Decode(&existing)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+		return
+	}
+
+	if !canModify(c, existing.AuthorID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own blogs"})
+		return
+	}
+
+	filter := bson.M{"_id": blogID}
 	result, err := blogCollection.DeleteOne(context.TODO(), filter)
 	if err != nil || result.DeletedCount == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting blog"})
 		return
 	}
 
+	releaseRecordFile(context.TODO(), existing.ImageKey, existing.ImageURL)
+
+	if err := revokeReputation(context.TODO(), blogID); err != nil {
+		log.Println("⚠️ Failed to take back reputation:", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Blog deleted successfully"})
 }
@@ -0,0 +1,483 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxCommentLength = 1000
+	repliesPreview   = 3 // replies shown under each comment in the answer's listing
+)
+
+var (
+	commentCollection *mongo.Collection
+	commentOnce       sync.Once
+)
+
+func getCommentCollection() *mongo.Collection {
+	commentOnce.Do(func() {
+		commentCollection = config.GetCollection("comments")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = commentCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "answerId", Value: 1}, {Key: "parentId", Value: 1}, {Key: "createdAt", Value: 1}}},
+			{Keys: bson.D{{Key: "parentId", Value: 1}, {Key: "createdAt", Value: 1}}},
+			{Keys: bson.D{{Key: "questionId", Value: 1}}},
+		})
+	})
+	return commentCollection
+}
+
+// commentText validates and trims the text of a new or edited comment
+func commentText(c *gin.Context, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment text is required"})
+		return "", false
+	}
+	if len([]rune(text)) > maxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comments can be at most 1000 characters"})
+		return "", false
+	}
+	return text, true
+}
+
+// pageParams reads ?page= and ?limit= with the given default page size
+func pageParams(c *gin.Context, defaultLimit int) (int, int) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = defaultLimit
+	}
+	return page, limit
+}
+
+// addCommentCount keeps the answer's comment counter in step with the comments collection
+func addCommentCount(ctx context.Context, answerID primitive.ObjectID, delta int) {
+	_, err := getAnswerCollection().UpdateOne(ctx,
+		bson.M{"_id": answerID},
+		bson.M{"$inc": bson.M{"commentCount": delta}},
+	)
+	if err != nil {
+		log.Println("⚠️ Failed to update comment count:", err)
+	}
+}
+
+// AddComment comments on an answer, or replies to a comment with parentId
+func AddComment(c *gin.Context) {
+	answerID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer ID"})
+		return
+	}
+
+	var request struct {
+		Text     string `json:"text"`
+		ParentID string `json:"parentId"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	text, ok := commentText(c, request.Text)
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	question, _, err := findAnswer(ctx, getQnaCollection(), answerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
+	}
+
+	comments := getCommentCollection()
+	comment := models.Comment{
+		ID:         primitive.NewObjectID(),
+		AnswerID:   answerID,
+		QuestionID: question.ID,
+		Text:       text,
+		PostedBy:   c.GetString("fullName"),
+		PostedByID: userID,
+		CreatedAt:  time.Now(),
+	}
+	if comment.PostedBy == "" {
+		comment.PostedBy = "Anonymous"
+	}
+
+	if request.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(request.ParentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent comment ID"})
+			return
+		}
+
+		var parent models.Comment
+		if err := comments.FindOne(ctx, bson.M{"_id": parentID, "answerId": answerID}).Decode(&parent); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return
+		}
+
+		// Only one level of nesting: replying to a reply joins its thread
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		comment.ParentID = &parentID
+	}
+
+	if _, err := comments.InsertOne(ctx, comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post comment"})
+		return
+	}
+
+	if comment.ParentID != nil {
+		if _, err := comments.UpdateOne(ctx, bson.M{"_id": *comment.ParentID}, bson.M{"$inc": bson.M{"replyCount": 1}}); err != nil {
+			log.Println("⚠️ Failed to update reply count:", err)
+		}
+	}
+	addCommentCount(ctx, answerID, 1)
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Comment posted successfully", "comment": comment})
+}
+
+// listComments returns one page of comments matching the filter, oldest first
+func listComments(ctx context.Context, filter bson.M, page, limit int) ([]models.Comment, int64, error) {
+	comments := getCommentCollection()
+
+	total, err := comments.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := comments.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := []models.Comment{}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+	return result, total, nil
+}
+
+// fillCommentVotes sets MyVote on the comments and their replies for the given user
+func fillCommentVotes(ctx context.Context, userID primitive.ObjectID, comments []models.Comment) error {
+	var ids []primitive.ObjectID
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+		for _, reply := range comment.Replies {
+			ids = append(ids, reply.ID)
+		}
+	}
+
+	votes, err := userVotes(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range comments {
+		comments[i].MyVote = votes[comments[i].ID]
+		for j := range comments[i].Replies {
+			comments[i].Replies[j].MyVote = votes[comments[i].Replies[j].ID]
+		}
+	}
+	return nil
+}
+
+func commentPagination(page, limit int, total int64) gin.H {
+	return gin.H{
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+		"hasMore":    int64(page*limit) < total,
+	}
+}
+
+// GetComments lists an answer's top-level comments, each with its first few replies
+func GetComments(c *gin.Context) {
+	answerID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer ID"})
+		return
+	}
+
+	page, limit := pageParams(c, 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	comments, total, err := listComments(ctx, bson.M{"answerId": answerID, "parentId": bson.M{"$exists": false}}, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		return
+	}
+
+	for i := range comments {
+		if comments[i].ReplyCount == 0 {
+			continue
+		}
+		replies, _, err := listComments(ctx, bson.M{"parentId": comments[i].ID}, 1, repliesPreview)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+			return
+		}
+		comments[i].Replies = replies
+	}
+
+	if userID, ok := currentUserID(c); ok {
+		if err := fillCommentVotes(ctx, userID, comments); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch votes"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": comments, "pagination": commentPagination(page, limit, total)})
+}
+
+// GetCommentReplies lists all replies to a comment
+func GetCommentReplies(c *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	page, limit := pageParams(c, 20)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	replies, total, err := listComments(ctx, bson.M{"parentId": commentID}, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch replies"})
+		return
+	}
+
+	if userID, ok := currentUserID(c); ok {
+		if err := fillCommentVotes(ctx, userID, replies); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch votes"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replies": replies, "pagination": commentPagination(page, limit, total)})
+}
+
+// EditComment lets the author change their comment
+func EditComment(c *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var request struct {
+		Text string `json:"text"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	text, ok := commentText(c, request.Text)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	comments := getCommentCollection()
+	var comment models.Comment
+	if err := comments.FindOne(ctx, bson.M{"_id": commentID}).Decode(&comment); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if !isOwner(c, comment.PostedByID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own comments"})
+		return
+	}
+
+	now := time.Now()
+	if _, err := comments.UpdateOne(ctx, bson.M{"_id": commentID}, bson.M{"$set": bson.M{"text": text, "editedAt": now}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully"})
+}
+
+// DeleteComment removes a comment along with its replies; the author or a moderator may delete it
+func DeleteComment(c *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	comments := getCommentCollection()
+	var comment models.Comment
+	if err := comments.FindOne(ctx, bson.M{"_id": commentID}).Decode(&comment); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if !canModify(c, comment.PostedByID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own comments"})
+		return
+	}
+
+	// The comment goes with its replies, and all of their votes
+	ids, err := comments.Distinct(ctx, "_id", bson.M{"$or": bson.A{bson.M{"_id": commentID}, bson.M{"parentId": commentID}}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	result, err := comments.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	if _, err := getVoteCollection().DeleteMany(ctx, bson.M{"targetId": bson.M{"$in": ids}, "targetType": models.VoteTargetComment}); err != nil {
+		log.Println("⚠️ Failed to delete votes:", err)
+	}
+
+	if comment.ParentID != nil {
+		if _, err := comments.UpdateOne(ctx, bson.M{"_id": *comment.ParentID}, bson.M{"$inc": bson.M{"replyCount": -1}}); err != nil {
+			log.Println("⚠️ Failed to update reply count:", err)
+		}
+	}
+	addCommentCount(ctx, comment.AnswerID, -int(result.DeletedCount))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// VoteComment up- or downvotes a comment. Comment votes don't earn reputation.
+func VoteComment(c *gin.Context) {
+	commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var request struct {
+		VoteType string `json:"voteType"` // "upvote", "downvote" or "none"
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	direction, ok := parseVoteType(request.VoteType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote type"})
+		return
+	}
+
+	voterID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	comments := getCommentCollection()
+	var comment models.Comment
+	if err := comments.FindOne(ctx, bson.M{"_id": commentID}).Decode(&comment); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	previous, current, err := castVote(ctx, voterID, commentID, models.VoteTargetComment, direction)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
+		return
+	}
+
+	// Keep the counters in step with the ledger
+	up, down := voteCounterDelta(previous, current)
+	if up != 0 || down != 0 {
+		update := bson.M{"$inc": bson.M{"upvotes": up, "downvotes": down}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := comments.FindOneAndUpdate(ctx, bson.M{"_id": commentID}, update, opts).Decode(&comment); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Vote recorded successfully",
+		"myVote":    current,
+		"upvotes":   comment.Upvotes,
+		"downvotes": comment.Downvotes,
+	})
+}
+
+// deleteComments removes the comments matching the filter along with their votes,
+// when the answer or question they belong to is deleted
+func deleteComments(ctx context.Context, filter interface{}) {
+	comments := getCommentCollection()
+
+	ids, err := comments.Distinct(ctx, "_id", filter)
+	if err != nil {
+		log.Println("⚠️ Failed to delete comments:", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	if _, err := comments.DeleteMany(ctx, filter); err != nil {
+		log.Println("⚠️ Failed to delete comments:", err)
+		return
+	}
+	if _, err := getVoteCollection().DeleteMany(ctx, bson.M{"targetId": bson.M{"$in": ids}}); err != nil {
+		log.Println("⚠️ Failed to delete votes:", err)
+	}
+}
@@ -2,7 +2,12 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +18,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -23,6 +29,18 @@ var (
 func getDocumentCollection() *mongo.Collection {
 	documentOnce.Do(func() {
 		documentCollection = config.GetCollection("documents")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = documentCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "branch", Value: 1}, {Key: "semester", Value: 1}, {Key: "subject", Value: 1}, {Key: "year", Value: 1}}},
+			{Keys: bson.D{{Key: "subject", Value: 1}}},
+			{Keys: bson.D{{Key: "uploaderId", Value: 1}}},
+			{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "downloads", Value: -1}, {Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "storageKey", Value: 1}}, Options: options.Index().SetSparse(true)},
+		})
 	})
 	return documentCollection
 }
@@ -35,10 +53,21 @@ func CreateDocument(c *gin.Context) {
 		return
 	}
 
-	// Parse form data (Limit: 10 MB)
+	uploaderID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse form data, refusing bodies that cannot hold a file within the limit
+	limitDocumentUpload(c)
 	err := c.Request.ParseMultipartForm(10 << 20)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size too large"})
+		status := http.StatusBadRequest
+		if errors.As(err, new(*http.MaxBytesError)) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"error": "File size too large"})
 		return
 	}
 
@@ -50,13 +79,20 @@ func CreateDocument(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Upload file to Cloudinary
-	fileURL, err := utils.UploadFile(file, header.Filename)
+	if header.Size > maxDocumentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size too large"})
+		return
+	}
+
+	// Upload file to storage
+	stored, err := utils.UploadFile(file, header.Filename)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not upload file"})
 		return
 	}
 
+	data := extractionData(file, header)
+
 	// Create document instance
 	doc := models.Document{
 		ID:         primitive.NewObjectID(),
@@ -65,9 +101,13 @@ func CreateDocument(c *gin.Context) {
 		Year:       c.PostForm("year"),
 		Branch:     c.PostForm("branch"),
 		Content:    c.PostForm("content"),
-		FileUrl:    fileURL,
+		Downloads:  0,
+		Extraction: models.ExtractionPending,
+		FileUrl:    stored.URL,
 		FileName:   header.Filename,
-		UploadedBy: c.PostForm("uploadedBy"),
+		StorageKey: stored.Key,
+		UploadedBy: c.GetString("fullName"),
+		UploaderID: uploaderID,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
@@ -75,15 +115,110 @@ func CreateDocument(c *gin.Context) {
 	// Insert into MongoDB
 	_, err = documentCollection.InsertOne(context.TODO(), doc)
 	if err != nil {
+		releaseFile(context.TODO(), stored.Key)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save document"})
 		return
 	}
 
+	enqueueExtraction(extractionJob{DocumentID: doc.ID, Data: data})
+
+	err = awardReputation(context.TODO(), uploaderID, pointsDocumentUploaded, models.ReputationDocumentUploaded, doc.ID, "document:"+doc.ID.Hex())
+	if err != nil {
+		log.Println("⚠️ Failed to award reputation:", err)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"message": "Document uploaded successfully", "document": doc})
 }
 
-// Get All Documents
+// maxDocumentSize is the largest file that can be uploaded as a document
+const maxDocumentSize = 10 << 20
+
+// limitDocumentUpload caps the request body at the document limit plus room for
+// the other form fields. ParseMultipartForm alone only limits what is kept in memory.
+func limitDocumentUpload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDocumentSize+1<<20)
+}
+
+// extractionData returns the uploaded file for the text extraction workers, or
+// nil when it is too big to queue in memory and they should download it instead
+func extractionData(file multipart.File, header *multipart.FileHeader) []byte {
+	if header.Size > maxExtractionFile {
+		return nil
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxExtractionFile))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// documentFacets are the fields documents can be filtered on and counted by
+var documentFacets = []string{"subject", "semester", "year", "branch"}
+
+// documentSorts maps each ?sort= mode to its order
+var documentSorts = map[string]bson.D{
+	"newest":  {{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+	"oldest":  {{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+	"popular": {{Key: "downloads", Value: -1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+}
+
+// documentFilters reads the facet filters from the query. ?uploader= takes a
+// user ID or the uploader's name. Several values of one field can be comma separated.
+func documentFilters(c *gin.Context) map[string]interface{} {
+	filters := map[string]interface{}{}
+
+	for _, field := range documentFacets {
+		var values []string
+		for _, v := range strings.Split(c.Query(field), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		switch len(values) {
+		case 0:
+		case 1:
+			filters[field] = values[0]
+		default:
+			filters[field] = bson.M{"$in": values}
+		}
+	}
+
+	if uploader := strings.TrimSpace(c.Query("uploader")); uploader != "" {
+		if id, err := primitive.ObjectIDFromHex(uploader); err == nil {
+			filters["uploaderId"] = id
+		} else {
+			filters["uploadedBy"] = uploader
+		}
+	}
+
+	return filters
+}
+
+// matchExcept builds a filter from all filters but one field's, so each facet
+// counts the values its own selection would switch to
+func matchExcept(filters map[string]interface{}, except string) bson.M {
+	match := bson.M{}
+	for field, value := range filters {
+		if field != except {
+			match[field] = value
+		}
+	}
+	return match
+}
+
+// Get All Documents, filtered by ?subject= ?semester= ?year= ?branch= ?uploader=,
+// sorted by ?sort=newest|oldest|popular, with facet counts for each filter
 func GetAllDocuments(c *gin.Context) {
+	listDocuments(c, documentFilters(c))
+}
+
+// listDocuments responds with one page of the documents matching filters, in
+// the ?sort= order, with facet counts for each filter
+func listDocuments(c *gin.Context, filters map[string]interface{}) {
 
 	documentCollection := getDocumentCollection()
 	if documentCollection == nil {
@@ -91,55 +226,150 @@ func GetAllDocuments(c *gin.Context) {
 		return
 	}
 
-	cursor, err := documentCollection.Find(context.TODO(), bson.M{})
+	sort, ok := documentSorts[c.DefaultQuery("sort", "newest")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use newest, oldest or popular"})
+		return
+	}
+
+	page, limit := pageParams(c, 20)
+	match := matchExcept(filters, "")
+
+	facets := bson.M{
+		"documents": bson.A{
+			bson.M{"$match": match},
+			bson.M{"$sort": sort},
+			bson.M{"$skip": (page - 1) * limit},
+			bson.M{"$limit": limit},
+			bson.M{"$project": bson.M{"text": 0}},
+		},
+		"total": bson.A{
+			bson.M{"$match": match},
+			bson.M{"$count": "count"},
+		},
+	}
+	for _, field := range documentFacets {
+		facets[field] = bson.A{
+			bson.M{"$match": matchExcept(filters, field)},
+			bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+			bson.M{"$match": bson.M{"_id": bson.M{"$nin": bson.A{nil, ""}}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$project": bson.M{"_id": 0, "value": "$_id", "count": 1}},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	cursor, err := documentCollection.Aggregate(ctx, mongo.Pipeline{{{Key: "$facet", Value: facets}}})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch documents"})
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	var documents []models.Document
-	for cursor.Next(context.TODO()) {
-		var doc models.Document
-		if err := cursor.Decode(&doc); err != nil { // Handle decode errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding document"})
-			return
+	type facetCount struct {
+		Value string `bson:"value" json:"value"`
+		Count int    `bson:"count" json:"count"`
+	}
+	var results []struct {
+		Documents []models.Document `bson:"documents"`
+		Total     []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Subject  []facetCount `bson:"subject"`
+		Semester []facetCount `bson:"semester"`
+		Year     []facetCount `bson:"year"`
+		Branch   []facetCount `bson:"branch"`
+	}
+	if err := cursor.All(ctx, &results); err != nil || len(results) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding document"})
+		return
+	}
+	result := results[0]
+
+	var total int64
+	if len(result.Total) > 0 {
+		total = result.Total[0].Count
+	}
+	if result.Documents == nil {
+		result.Documents = []models.Document{}
+	}
+
+	nonNil := func(counts []facetCount) []facetCount {
+		if counts == nil {
+			return []facetCount{}
 		}
-		documents = append(documents, doc)
+		return counts
 	}
 
-	c.JSON(http.StatusOK, gin.H{"documents": documents})
+	c.JSON(http.StatusOK, gin.H{
+		"documents": result.Documents,
+		"facets": gin.H{
+			"subject":  nonNil(result.Subject),
+			"semester": nonNil(result.Semester),
+			"year":     nonNil(result.Year),
+			"branch":   nonNil(result.Branch),
+		},
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+			"hasMore":    int64(page*limit) < total,
+		},
+	})
 }
 
-// Get Documents by Branch
-func GetDocumentsByBranch(c *gin.Context) {
+// Get a single Document. Before /documents/branch/:branch existed branches were
+// listed from /documents/:branch, so an ID that is not an ObjectID is still
+// treated as a branch for older clients.
+func GetDocument(c *gin.Context) {
 
 	documentCollection := getDocumentCollection()
-	if documentCollection == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection is not initialized"})
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		listBranchDocuments(c, c.Param("id"))
 		return
 	}
 
-	branch := c.Param("branch")
+	var doc models.Document
+	if err := documentCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&doc); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"document": doc})
+}
 
-	cursor, err := documentCollection.Find(context.TODO(), bson.M{"branch": branch})
+// DownloadDocument counts a download and redirects to the file
+func DownloadDocument(c *gin.Context) {
+
+	documentCollection := getDocumentCollection()
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not fetch documents"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	var documents []models.Document
-	for cursor.Next(context.TODO()) {
-		var doc models.Document
-		if err := cursor.Decode(&doc); err != nil { // Handle decode errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding document"})
-			return
-		}
-		documents = append(documents, doc)
+	var doc models.Document
+	update := bson.M{"$inc": bson.M{"downloads": 1}}
+	if err := documentCollection.FindOneAndUpdate(context.TODO(), bson.M{"_id": objID}, update).Decode(&doc); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"documents": documents})
+	c.Redirect(http.StatusFound, doc.FileUrl)
+}
+
+// Get Documents by Branch, paginated and filtered like GetAllDocuments
+func GetDocumentsByBranch(c *gin.Context) {
+	listBranchDocuments(c, c.Param("branch"))
+}
+
+func listBranchDocuments(c *gin.Context, branch string) {
+	filters := documentFilters(c)
+	filters["branch"] = branch
+	listDocuments(c, filters)
 }
 
 // Update Document
@@ -158,21 +388,97 @@ func UpdateDocument(c *gin.Context) {
 		return
 	}
 
-	var updatedData models.Document
-	if err := c.BindJSON(&updatedData); err != nil {
+	var existing models.Document
+	err = documentCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&existing)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	if !canModify(c, existing.UploaderID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own documents"})
+		return
+	}
+
+	var updatedData struct {
+		Subject  string `json:"subject" form:"subject"`
+		Semester string `json:"semester" form:"semester"`
+		Year     string `json:"year" form:"year"`
+		Branch   string `json:"branch" form:"branch"`
+		Content  string `json:"content" form:"content"`
+	}
+	limitDocumentUpload(c)
+	if err := c.ShouldBind(&updatedData); err != nil {
+		if errors.As(err, new(*http.MaxBytesError)) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	updatedData.UpdatedAt = time.Now()
+	// Ownership is left untouched; the file changes only when a new one is uploaded
+	fields := bson.M{"updatedAt": time.Now()}
+	if updatedData.Subject != "" {
+		fields["subject"] = updatedData.Subject
+	}
+	if updatedData.Semester != "" {
+		fields["semester"] = updatedData.Semester
+	}
+	if updatedData.Year != "" {
+		fields["year"] = updatedData.Year
+	}
+	if updatedData.Branch != "" {
+		fields["branch"] = updatedData.Branch
+	}
+	if updatedData.Content != "" {
+		fields["content"] = updatedData.Content
+	}
+
+	filter := bson.M{"_id": objID}
+	update := bson.M{"$set": fields}
+
+	// A multipart request may carry a replacement file
+	var stored utils.StoredFile
+	var data []byte
+	if file, header, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+
+		if header.Size > maxDocumentSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File size too large"})
+			return
+		}
+
+		stored, err = utils.UploadFile(file, header.Filename)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not upload file"})
+			return
+		}
+
+		data = extractionData(file, header)
+
+		fields["fileUrl"] = stored.URL
+		fields["fileName"] = header.Filename
+		fields["storageKey"] = stored.Key
+		fields["extraction"] = models.ExtractionPending
+		update["$unset"] = bson.M{"text": "", "preview": ""}
 
-	update := bson.M{"$set": updatedData}
-	result, err := documentCollection.UpdateOne(context.TODO(), bson.M{"_id": objID}, update)
+		// only replace the file we are about to release
+		filter["fileUrl"] = existing.FileUrl
+	}
+
+	result, err := documentCollection.UpdateOne(context.TODO(), filter, update)
 	if err != nil || result.MatchedCount == 0 {
+		releaseFile(context.TODO(), stored.Key)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update document"})
 		return
 	}
 
+	if stored.Key != "" {
+		releaseRecordFile(context.TODO(), existing.StorageKey, existing.FileUrl)
+		enqueueExtraction(extractionJob{DocumentID: objID, Data: data})
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Document updated successfully"})
 }
 
@@ -192,11 +498,29 @@ func DeleteDocument(c *gin.Context) {
 		return
 	}
 
+	var existing models.Document
+	err = documentCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&existing)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	if !canModify(c, existing.UploaderID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own documents"})
+		return
+	}
+
 	result, err := documentCollection.DeleteOne(context.TODO(), bson.M{"_id": objID})
 	if err != nil || result.DeletedCount == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete document"})
 		return
 	}
 
+	releaseRecordFile(context.TODO(), existing.StorageKey, existing.FileUrl)
+
+	if err := revokeReputation(context.TODO(), objID); err != nil {
+		log.Println("⚠️ Failed to take back reputation:", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
 }
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+)
+
+// ServeLocalFile serves files kept on disk by utils.LocalStorage. They are
+// public, and always sent as downloads so an uploaded HTML or SVG file never
+// runs as a page on the API's origin.
+func ServeLocalFile(c *gin.Context) {
+	local, ok := utils.GetStorage().(*utils.LocalStorage)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	path, err := local.Path(key)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.FileAttachment(path, filepath.Base(path))
+}
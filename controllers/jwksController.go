@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+)
+
+// GetJWKS publishes the public keys other services use to verify PrepPortal tokens
+func GetJWKS(c *gin.Context) {
+	jwks, err := utils.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Signing keys are not available"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
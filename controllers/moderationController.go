@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultReportHideThreshold = 3
+	defaultReportMinReputation = 15
+)
+
+var (
+	reportCollection *mongo.Collection
+	reportOnce       sync.Once
+)
+
+func getReportCollection() *mongo.Collection {
+	reportOnce.Do(func() {
+		reportCollection = config.GetCollection("reports")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = reportCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			// One report per user per item
+			{
+				Keys:    bson.D{{Key: "targetId", Value: 1}, {Key: "reporterId", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}},
+		})
+	})
+	return reportCollection
+}
+
+// migrateEmbeddedReports moves reports still embedded in questions (from before
+// the reports collection) into it as open reports, so they reach the moderation
+// queue, and drops the embedded copies. Reporters were not checked back then,
+// so these reports never hide a question by themselves.
+func migrateEmbeddedReports(qnaCollection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"reports": 1})
+	cursor, err := qnaCollection.Find(ctx, bson.M{"reports.0": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		log.Println("⚠️ Failed to load embedded reports:", err)
+		return
+	}
+
+	var questions []struct {
+		ID      primitive.ObjectID `bson:"_id"`
+		Reports []struct {
+			ReportedBy primitive.ObjectID `bson:"reportedBy"`
+			Reason     string             `bson:"reason"`
+			CreatedAt  time.Time          `bson:"createdAt"`
+		} `bson:"reports"`
+	}
+	if err := cursor.All(ctx, &questions); err != nil {
+		log.Println("⚠️ Failed to load embedded reports:", err)
+		return
+	}
+
+	reports := getReportCollection()
+	migrated := 0
+	for _, question := range questions {
+		var docs []interface{}
+		for _, embedded := range question.Reports {
+			docs = append(docs, models.ContentReport{
+				ID:         primitive.NewObjectID(),
+				TargetID:   question.ID,
+				TargetType: models.ReportTargetQuestion,
+				ReporterID: embedded.ReportedBy,
+				Reason:     embedded.Reason,
+				Status:     models.ReportOpen,
+				CreatedAt:  embedded.CreatedAt,
+			})
+		}
+
+		// Repeated reports by one user collapse into one, as they do today
+		result, err := reports.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		var bulkErr mongo.BulkWriteException
+		if err != nil && !(errors.As(err, &bulkErr) && onlyDuplicateKeyErrors(bulkErr)) {
+			log.Println("⚠️ Failed to migrate reports of question", question.ID.Hex(), err)
+			continue
+		}
+		if result != nil {
+			migrated += len(result.InsertedIDs)
+		}
+
+		if _, err := qnaCollection.UpdateOne(ctx, bson.M{"_id": question.ID}, bson.M{"$unset": bson.M{"reports": ""}}); err != nil {
+			log.Println("⚠️ Failed to remove embedded reports:", err)
+		}
+	}
+
+	// Questions without reports still carry an empty or null list
+	if _, err := qnaCollection.UpdateMany(ctx, bson.M{"reports": bson.M{"$exists": true}, "reports.0": bson.M{"$exists": false}}, bson.M{"$unset": bson.M{"reports": ""}}); err != nil {
+		log.Println("⚠️ Failed to remove empty report lists:", err)
+	}
+
+	if migrated > 0 {
+		log.Printf("✅ Moved %d embedded question reports to the moderation queue", migrated)
+	}
+}
+
+func onlyDuplicateKeyErrors(err mongo.BulkWriteException) bool {
+	for _, writeErr := range err.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return err.WriteConcernError == nil
+}
+
+// reportHideThreshold is how many distinct open trusted reports hide an item until a
+// moderator looks at it, set with REPORT_HIDE_THRESHOLD
+func reportHideThreshold() int64 {
+	if n, err := strconv.Atoi(os.Getenv("REPORT_HIDE_THRESHOLD")); err == nil && n > 0 {
+		return int64(n)
+	}
+	return defaultReportHideThreshold
+}
+
+// reportMinReputation is the reputation a reporter needs for their report to
+// count toward hiding an item, set with REPORT_MIN_REPUTATION. It keeps a few
+// fresh accounts from hiding anything they like.
+func reportMinReputation() int {
+	if n, err := strconv.Atoi(os.Getenv("REPORT_MIN_REPUTATION")); err == nil && n >= 0 {
+		return n
+	}
+	return defaultReportMinReputation
+}
+
+// reportTarget is the reported question, answer or blog
+type reportTarget struct {
+	Type       string
+	ID         primitive.ObjectID
+	QuestionID primitive.ObjectID // the question an answer belongs to
+	OwnerID    primitive.ObjectID
+	Status     string
+	Text       string
+}
+
+// loadReportTarget looks up reportable content by type and ID
+func loadReportTarget(ctx context.Context, targetType string, id primitive.ObjectID) (*reportTarget, error) {
+	target := &reportTarget{Type: targetType, ID: id}
+
+	switch targetType {
+	case models.ReportTargetQuestion:
+		var question models.Qna
+		if err := getQnaCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&question); err != nil {
+			return nil, err
+		}
+		target.QuestionID = question.ID
+		target.OwnerID = question.PostedByID
+		target.Status = question.ModerationStatus
+		target.Text = question.Question
+
+	case models.ReportTargetAnswer:
+		question, answer, err := findAnswer(ctx, getQnaCollection(), id)
+		if err != nil {
+			return nil, err
+		}
+		target.QuestionID = question.ID
+		target.OwnerID = answer.PostedByID
+		target.Status = answer.ModerationStatus
+		target.Text = answer.Text
+
+	case models.ReportTargetBlog:
+		var blog models.Blog
+		if err := GetBlogCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&blog); err != nil {
+			return nil, err
+		}
+		target.OwnerID = blog.AuthorID
+		target.Status = blog.ModerationStatus
+		target.Text = blog.Title
+
+	default:
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return target, nil
+}
+
+// setModerationStatus hides, removes or (with "") restores the target. With
+// onlyIfVisible it leaves content a moderator already acted on untouched.
+func setModerationStatus(ctx context.Context, target *reportTarget, status string, onlyIfVisible bool) error {
+	collection := getQnaCollection()
+	switch target.Type {
+	case models.ReportTargetAnswer:
+		collection = getAnswerCollection()
+	case models.ReportTargetBlog:
+		collection = GetBlogCollection()
+	}
+
+	filter := bson.M{"_id": target.ID}
+	if onlyIfVisible {
+		filter["moderationStatus"] = bson.M{"$exists": false}
+	}
+
+	update := bson.M{"$set": bson.M{"moderationStatus": status}}
+	if status == "" {
+		update = bson.M{"$unset": bson.M{"moderationStatus": ""}}
+	}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// submitReport records the caller's report and hides the target once enough
+// different users have reported it
+func submitReport(c *gin.Context, targetType string) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	reporterID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BindJSON(&request); err != nil || strings.TrimSpace(request.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	target, err := loadReportTarget(ctx, targetType, targetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content not found"})
+		return
+	}
+
+	if target.OwnerID == reporterID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own content"})
+		return
+	}
+
+	var reporter models.User
+	err = getUserCollection().FindOne(ctx, bson.M{"_id": reporterID}, options.FindOne().SetProjection(bson.M{"reputation": 1})).Decode(&reporter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit report"})
+		return
+	}
+
+	report := models.ContentReport{
+		ID:         primitive.NewObjectID(),
+		TargetID:   target.ID,
+		TargetType: targetType,
+		ReporterID: reporterID,
+		Reason:     strings.TrimSpace(request.Reason),
+		Trusted:    reporter.Reputation >= reportMinReputation(),
+		Status:     models.ReportOpen,
+		CreatedAt:  time.Now(),
+	}
+	if targetType == models.ReportTargetAnswer {
+		report.QuestionID = target.QuestionID
+	}
+
+	reports := getReportCollection()
+	if _, err := reports.InsertOne(ctx, report); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit report"})
+		return
+	}
+
+	hidden := target.Status == models.ModerationHidden
+	// Every report reaches the moderation queue, only trusted ones hide the item
+	open, err := reports.CountDocuments(ctx, bson.M{"targetId": target.ID, "status": models.ReportOpen, "trusted": true})
+	if err != nil {
+		log.Println("⚠️ Failed to count reports:", err)
+	} else if target.Status == "" && open >= reportHideThreshold() {
+		if err := setModerationStatus(ctx, target, models.ModerationHidden, true); err != nil {
+			log.Println("⚠️ Failed to hide reported content:", err)
+		} else {
+			hidden = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report submitted", "hidden": hidden})
+}
+
+// ReportQuestion reports a question to the moderators
+func ReportQuestion(c *gin.Context) {
+	submitReport(c, models.ReportTargetQuestion)
+}
+
+// ReportAnswer reports an answer to the moderators
+func ReportAnswer(c *gin.Context) {
+	submitReport(c, models.ReportTargetAnswer)
+}
+
+// ReportBlog reports a blog to the moderators
+func ReportBlog(c *gin.Context) {
+	submitReport(c, models.ReportTargetBlog)
+}
+
+// moderationQueueItem groups the reports about one piece of content
+type moderationQueueItem struct {
+	TargetID         primitive.ObjectID `bson:"_id" json:"targetId"`
+	TargetType       string             `bson:"targetType" json:"targetType"`
+	QuestionID       primitive.ObjectID `bson:"questionId,omitempty" json:"questionId,omitempty"`
+	Reports          int                `bson:"reports" json:"reports"`
+	Reasons          []string           `bson:"reasons" json:"reasons"`
+	FirstReportedAt  time.Time          `bson:"firstReportedAt" json:"firstReportedAt"`
+	LastReportedAt   time.Time          `bson:"lastReportedAt" json:"lastReportedAt"`
+	Text             string             `bson:"-" json:"text"`
+	OwnerID          primitive.ObjectID `bson:"-" json:"ownerId"`
+	ModerationStatus string             `bson:"-" json:"moderationStatus"`
+}
+
+// GetModerationQueue lists content with open reports, most reported first
+func GetModerationQueue(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	match := bson.M{"status": models.ReportOpen}
+	if targetType := c.Query("type"); targetType != "" {
+		match["targetType"] = targetType
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$targetId",
+			"targetType":      bson.M{"$first": "$targetType"},
+			"questionId":      bson.M{"$first": "$questionId"},
+			"reports":         bson.M{"$sum": 1},
+			"reasons":         bson.M{"$push": "$reason"},
+			"firstReportedAt": bson.M{"$min": "$createdAt"},
+			"lastReportedAt":  bson.M{"$max": "$createdAt"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "reports", Value: -1}, {Key: "lastReportedAt", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	cursor, err := getReportCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation queue"})
+		return
+	}
+
+	items := []moderationQueueItem{}
+	if err := cursor.All(ctx, &items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding data"})
+		return
+	}
+
+	for i := range items {
+		target, err := loadReportTarget(ctx, items[i].TargetType, items[i].TargetID)
+		if err != nil {
+			continue // deleted since it was reported
+		}
+		items[i].Text = target.Text
+		items[i].OwnerID = target.OwnerID
+		items[i].ModerationStatus = target.Status
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// ModerateContent applies a moderator's decision on reported content:
+//   - dismiss closes the reports and unhides content that was hidden automatically
+//   - restore makes hidden or removed content visible again
+//   - remove takes the content down
+func ModerateContent(c *gin.Context) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	moderatorID, _ := currentUserID(c)
+	action := c.Param("action")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	target, err := loadReportTarget(ctx, c.Param("type"), targetID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load content"})
+		return
+	}
+
+	status, reportStatus := target.Status, models.ReportDismissed
+	switch action {
+	case "dismiss":
+		if status == models.ModerationHidden {
+			status = ""
+		}
+	case "restore":
+		status = ""
+	case "remove":
+		status, reportStatus = models.ModerationRemoved, models.ReportActioned
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action, use dismiss, restore or remove"})
+		return
+	}
+
+	if status != target.Status {
+		if err := setModerationStatus(ctx, target, status, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update content"})
+			return
+		}
+	}
+
+	now := time.Now()
+	result, err := getReportCollection().UpdateMany(ctx,
+		bson.M{"targetId": target.ID, "status": models.ReportOpen},
+		bson.M{"$set": bson.M{"status": reportStatus, "resolvedAt": now, "resolvedBy": moderatorID}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Moderation action applied",
+		"moderationStatus": status,
+		"reportsClosed":    result.ModifiedCount,
+	})
+}
@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errUnverifiedProviderEmail = errors.New("the provider has not verified this email address")
+	errUnverifiedAccount       = errors.New("an account with this email already exists, log in with your password (or reset it) and link the provider from your account")
+	errIdentityTaken           = errors.New("this provider account is already linked to another user")
+)
+
+// oidcBrowserCookie holds a secret tying an in-flight login to the browser that
+// started it, so a login or link URL handed to someone else cannot be completed
+const oidcBrowserCookie = "oidc_browser"
+
+// bindOIDCBrowser sets the browser cookie and returns its secret
+func bindOIDCBrowser(c *gin.Context, provider *utils.OIDCProvider) (string, error) {
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	setOIDCBrowserCookie(c, provider, secret, int(utils.OIDCStateTTL.Seconds()))
+	return secret, nil
+}
+
+func setOIDCBrowserCookie(c *gin.Context, provider *utils.OIDCProvider, value string, maxAge int) {
+	secure := strings.HasPrefix(provider.OAuth2.RedirectURL, "https://")
+	// Lax still sends it on the provider's redirect back to the callback
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBrowserCookie, value, maxAge, "/auth/oidc", "", secure, true)
+}
+
+// OIDCLogin redirects the browser to the provider's consent page
+func OIDCLogin(c *gin.Context) {
+	provider, ok := utils.GetOIDCProvider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	secret, err := bindOIDCBrowser(c, provider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	state, err := utils.NewOIDCState(ctx, provider.Name, "", secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier))
+}
+
+// OIDCLink starts linking a provider to the signed-in user's account. It answers
+// with the provider URL for the web app to open, since the browser navigation
+// itself cannot carry the access token. The web app must send this request with
+// credentials so the browser keeps the cookie the callback checks.
+func OIDCLink(c *gin.Context) {
+	provider, ok := utils.GetOIDCProvider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	secret, err := bindOIDCBrowser(c, provider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking"})
+		return
+	}
+
+	state, err := utils.NewOIDCState(ctx, provider.Name, userID.Hex(), secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier)})
+}
+
+// OIDCCallback completes the login, linking or creating the local user
+func OIDCCallback(c *gin.Context) {
+	provider, ok := utils.GetOIDCProvider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	if reason := c.Query("error"); reason != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was cancelled", "details": reason})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	state, err := utils.ConsumeOIDCState(ctx, c.Query("state"), provider.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login, please try again"})
+		return
+	}
+
+	// The login must finish in the browser that started it
+	secret, _ := c.Cookie(oidcBrowserCookie)
+	setOIDCBrowserCookie(c, provider, "", -1)
+	if !state.FromBrowser(secret) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login, please try again"})
+		return
+	}
+
+	identity, err := provider.Exchange(ctx, c.Query("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not verify login with provider"})
+		return
+	}
+
+	if state.LinkUserID != "" {
+		userID, _ := primitive.ObjectIDFromHex(state.LinkUserID)
+		err := linkExternalIdentity(ctx, userID, identity)
+		if errors.Is(err, errIdentityTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link provider"})
+			return
+		}
+		respondOIDCLogin(c, gin.H{"linked": true, "provider": provider.Name, "message": "Provider linked"})
+		return
+	}
+
+	if identity.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The provider did not share an email address"})
+		return
+	}
+
+	user, err := findOrLinkExternalUser(ctx, identity)
+	if errors.Is(err, errUnverifiedProviderEmail) || errors.Is(err, errUnverifiedAccount) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	// Accounts with TOTP still need their second factor
+	if user.TOTPEnabled {
+		challengeToken, err := utils.GenerateChallengeToken(user.ID.Hex())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+			return
+		}
+		respondOIDCLogin(c, gin.H{"twoFactorRequired": true, "challengeToken": challengeToken})
+		return
+	}
+
+	token, refreshToken, err := issueTokens(c, ctx, *user, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	respondOIDCLogin(c, gin.H{"token": token, "refreshToken": refreshToken})
+}
+
+// respondOIDCLogin hands the result to the web app through the URL fragment when
+// OIDC_SUCCESS_REDIRECT is set, so tokens never reach server logs; otherwise it answers JSON
+func respondOIDCLogin(c *gin.Context, result gin.H) {
+	target := os.Getenv("OIDC_SUCCESS_REDIRECT")
+	if target == "" {
+		if _, ok := result["message"]; !ok {
+			result["message"] = "Login successful"
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	fragment := url.Values{}
+	for key, value := range result {
+		switch v := value.(type) {
+		case string:
+			fragment.Set(key, v)
+		case bool:
+			if v {
+				fragment.Set(key, "true")
+			}
+		}
+	}
+
+	c.Redirect(http.StatusFound, strings.TrimRight(target, "#")+"#"+fragment.Encode())
+}
+
+// canAutoLink decides whether a provider login may be linked to the existing
+// account with the same email. Both sides must have verified the address:
+// otherwise whoever registered it first with a password of their choosing
+// would keep access to the account once its owner signs in with the provider.
+func canAutoLink(user *models.User, identity *utils.ExternalIdentity) error {
+	if !identity.EmailVerified {
+		return errUnverifiedProviderEmail
+	}
+	if !user.EmailVerified {
+		return errUnverifiedAccount
+	}
+	return nil
+}
+
+// findOrLinkExternalUser returns the user already linked to the identity, links it to
+// an existing account with the same verified email, or creates a new account
+func findOrLinkExternalUser(ctx context.Context, identity *utils.ExternalIdentity) (*models.User, error) {
+	userCollection := getUserCollection()
+
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"identities": bson.M{"$elemMatch": bson.M{
+		"provider": identity.Provider,
+		"subject":  identity.Subject,
+	}}}).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	// An unverified address could belong to anyone, so it neither links nor claims one
+	if !identity.EmailVerified {
+		return nil, errUnverifiedProviderEmail
+	}
+
+	email := normalizeEmail(identity.Email)
+	link := models.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+		LinkedAt: time.Now(),
+	}
+
+	err = userCollection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(emailCollation)).Decode(&user)
+	if err == nil {
+		if err := canAutoLink(&user, identity); err != nil {
+			return nil, err
+		}
+
+		_, err = userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+			"$push": bson.M{"identities": link},
+			"$set":  bson.M{"updatedAt": time.Now()},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		user.Identities = append(user.Identities, link)
+		return &user, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	fullName := identity.Name
+	if fullName == "" {
+		fullName, _, _ = strings.Cut(email, "@")
+	}
+
+	now := time.Now()
+	user = models.User{
+		ID:            primitive.NewObjectID(),
+		FullName:      fullName,
+		Email:         email,
+		EmailVerified: true,
+		Role:          models.RoleStudent,
+		Identities:    []models.Identity{link},
+		Contributions: []primitive.ObjectID{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if _, err := userCollection.InsertOne(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// linkExternalIdentity links the identity to a signed-in user, who proved they
+// own the account, so the emails do not have to match
+func linkExternalIdentity(ctx context.Context, userID primitive.ObjectID, identity *utils.ExternalIdentity) error {
+	userCollection := getUserCollection()
+
+	var owner models.User
+	err := userCollection.FindOne(ctx, bson.M{"identities": bson.M{"$elemMatch": bson.M{
+		"provider": identity.Provider,
+		"subject":  identity.Subject,
+	}}}).Decode(&owner)
+	if err == nil {
+		if owner.ID == userID {
+			return nil
+		}
+		return errIdentityTaken
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	link := models.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    normalizeEmail(identity.Email),
+		LinkedAt: time.Now(),
+	}
+	result, err := userCollection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$push": bson.M{"identities": link},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errIdentityTaken
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
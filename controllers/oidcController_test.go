@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"golang.org/x/oauth2"
+)
+
+func TestCanAutoLink(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		accountVerified  bool
+		providerVerified bool
+		want             error
+	}{
+		{"both verified", true, true, nil},
+		{"provider email unverified", true, false, errUnverifiedProviderEmail},
+		{"local account unverified", false, true, errUnverifiedAccount},
+		{"neither verified", false, false, errUnverifiedProviderEmail},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			user := &models.User{Email: "ada@example.com", EmailVerified: tc.accountVerified}
+			identity := &utils.ExternalIdentity{Provider: "google", Subject: "1", Email: "ada@example.com", EmailVerified: tc.providerVerified}
+
+			if err := canAutoLink(user, identity); !errors.Is(err, tc.want) {
+				t.Fatalf("canAutoLink = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackBrowserCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	exchanges := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges++
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+	}))
+	defer tokenServer.Close()
+
+	provider := &utils.OIDCProvider{Name: "cookietest", OAuth2: oauth2.Config{
+		ClientID:    "prepportal",
+		RedirectURL: "http://localhost/auth/oidc/cookietest/callback",
+		Endpoint:    oauth2.Endpoint{AuthURL: tokenServer.URL, TokenURL: tokenServer.URL, AuthStyle: oauth2.AuthStyleInParams},
+	}}
+	utils.RegisterOIDCProvider(provider)
+	utils.SetOIDCStateStore(utils.NewMemoryOIDCStateStore())
+	t.Cleanup(func() { utils.SetOIDCStateStore(utils.MongoOIDCStateStore{}) })
+
+	router := gin.New()
+	router.GET("/auth/oidc/:provider/login", OIDCLogin)
+	router.GET("/auth/oidc/:provider/callback", OIDCCallback)
+
+	for _, tc := range []struct {
+		name          string
+		cookie        func(login *http.Cookie) *http.Cookie
+		wantStatus    int
+		wantExchanges int
+	}{
+		{"missing cookie", func(*http.Cookie) *http.Cookie { return nil }, http.StatusBadRequest, 0},
+		{"mismatched cookie", func(login *http.Cookie) *http.Cookie {
+			return &http.Cookie{Name: login.Name, Value: "someone-else"}
+		}, http.StatusBadRequest, 0},
+		// the code is fake, so a matching cookie gets as far as the provider rejecting it
+		{"matching cookie", func(login *http.Cookie) *http.Cookie { return login }, http.StatusUnauthorized, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exchanges = 0
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/cookietest/login", nil))
+			if w.Code != http.StatusFound {
+				t.Fatalf("login status = %d", w.Code)
+			}
+			redirect, err := url.Parse(w.Header().Get("Location"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != oidcBrowserCookie || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+				t.Fatalf("login cookies = %+v", cookies)
+			}
+
+			query := url.Values{"state": {redirect.Query().Get("state")}, "code": {"attacker-code"}}
+			req := httptest.NewRequest(http.MethodGet, "/auth/oidc/cookietest/callback?"+query.Encode(), nil)
+			if cookie := tc.cookie(cookies[0]); cookie != nil {
+				req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+			}
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.wantStatus {
+				t.Fatalf("callback status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+			}
+			if exchanges != tc.wantExchanges {
+				t.Fatalf("code exchanges = %d, want %d", exchanges, tc.wantExchanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTTL  = time.Hour
+	minPasswordLength = 8
+)
+
+// frontendBaseURL is where users land when following emailed links meant for the web app
+func frontendBaseURL() string {
+	if base := os.Getenv("FRONTEND_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return "http://localhost:5173"
+}
+
+// ForgotPassword emails a one-time reset link. It always reports success so
+// it cannot be used to find out which emails are registered.
+func ForgotPassword(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := c.BindJSON(&request); err != nil || request.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"email": strings.TrimSpace(request.Email)}, options.FindOne().SetCollation(emailCollation)).Decode(&user)
+	if err == nil {
+		if err := sendPasswordResetEmail(ctx, user); err != nil {
+			log.Println("⚠️ Failed to send password reset email:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If that email is registered, a reset link has been sent"})
+}
+
+func sendPasswordResetEmail(ctx context.Context, user models.User) error {
+	token, err := utils.CreateUserToken(ctx, user.ID, models.TokenPurposePasswordReset, user.Email, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", frontendBaseURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset your PrepPortal password. Open the link below to choose a new one:\n\n%s\n\nThe link expires in one hour and can only be used once. If you did not ask for this, you can ignore this email.", user.FullName, link)
+
+	return utils.SendMail(user.Email, "Reset your PrepPortal password", body)
+}
+
+// ResetPassword sets a new password using an emailed reset token and logs out every session
+func ResetPassword(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	var request struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := c.BindJSON(&request); err != nil || request.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if len(request.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", minPasswordLength)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := utils.ConsumeUserToken(ctx, request.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+
+	hashedPassword, err := HashPassword(request.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error hashing password"})
+		return
+	}
+
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": token.UserID},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+
+	// Anyone holding the old password may still be logged in somewhere
+	if err := utils.RevokeAllSessions(ctx, token.UserID); err != nil {
+		log.Println("⚠️ Failed to revoke sessions after password reset:", err)
+	}
+	if err := utils.RevokeUserTokens(ctx, token.UserID, models.TokenPurposePasswordReset); err != nil {
+		log.Println("⚠️ Failed to revoke reset tokens:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please log in again"})
+}
+
+// ChangePassword replaces the password of the authenticated user after checking the current one.
+// Every other session is logged out and a fresh token pair is returned.
+func ChangePassword(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if len(request.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Password must be at least %d characters", minPasswordLength)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, ctx, user, request.CurrentPassword) {
+		return
+	}
+
+	hashedPassword, err := HashPassword(request.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error hashing password"})
+		return
+	}
+
+	_, err = userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	if err := utils.RevokeAllSessions(ctx, user.ID); err != nil {
+		log.Println("⚠️ Failed to revoke sessions after password change:", err)
+	}
+
+	token, refreshToken, err := issueTokens(c, ctx, user, c.GetBool("mfa"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully", "token": token, "refreshToken": refreshToken})
+}
+
+// checkCurrentPassword confirms the signed-in user's password, counting guesses
+// against the login throttle so a stolen access token cannot be used to find
+// it. It answers the request itself when the password is refused.
+func checkCurrentPassword(c *gin.Context, ctx context.Context, user models.User, password string) bool {
+	throttle := utils.GetLoginThrottle()
+	wait, err := throttle.Attempt(ctx, user.Email, c.ClientIP())
+	if err != nil {
+		log.Println("⚠️ Login throttle check failed:", err)
+	}
+	if wait > 0 {
+		tooManyAttempts(c, wait)
+		return false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return false
+	}
+
+	if err := throttle.Success(ctx, user.Email, c.ClientIP()); err != nil {
+		log.Println("⚠️ Failed to reset login attempts:", err)
+	}
+	return true
+}
@@ -3,8 +3,10 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +27,9 @@ var (
 func getQnaCollection() *mongo.Collection {
 	qnaOnce.Do(func() {
 		qnaCollection = config.GetCollection("qna")
+		createQnaIndexes(qnaCollection)
+		backfillQnaRanking(qnaCollection)
+		migrateEmbeddedReports(qnaCollection)
 	})
 	return qnaCollection
 }
@@ -50,9 +55,31 @@ func AskQuestion(c *gin.Context) {
 	}
 
 	qna.ID = primitive.NewObjectID()
+	qna.PostedByID, _ = currentUserID(c)
 	qna.CreatedAt = time.Now()
 	qna.Upvotes = 0
 	qna.Downvotes = 0
+	qna.Answers = []models.Answer{}
+	qna.AnswerCount = 0
+	qna.AcceptedAnswerID = nil
+	qna.Resolved = false
+	qna.Score = 0
+	qna.HotScore = hotScore(0, qna.CreatedAt)
+	qna.LastActivityAt = qna.CreatedAt
+	qna.ViewCount = 0
+	qna.ModerationStatus = ""
+	qna.DuplicateOfID = nil
+	qna.ClosedBy = nil
+	qna.ClosedAt = nil
+	qna.Tags = normalizeTags(qna.Tags)
+	qna.Branch = strings.TrimSpace(qna.Branch)
+	qna.Semester = strings.TrimSpace(qna.Semester)
+	qna.Subject = strings.TrimSpace(qna.Subject)
+
+	if len(qna.Tags) > maxQuestionTags {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A question can have at most %d tags", maxQuestionTags)})
+		return
+	}
 
 	fmt.Println("Retrieved Full Name:", fullName)
 
@@ -68,7 +95,14 @@ func AskQuestion(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Question posted successfully", "question": qna})
+	// Point the asker at questions that may already have their answer
+	duplicates, err := similarQuestions(context.TODO(), qna.Question, qna.ID)
+	if err != nil {
+		log.Println("⚠️ Failed to look for duplicates:", err)
+		duplicates = []similarQuestion{}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Question posted successfully", "question": qna, "possibleDuplicates": duplicates})
 }
 
 // Answer a Question
@@ -107,43 +141,42 @@ func AnswerQuestion(c *gin.Context) {
 	}
 
 	answer.ID = primitive.NewObjectID()
+	answer.PostedByID, _ = currentUserID(c)
 	answer.CreatedAt = time.Now()
 	answer.Upvotes = 0
 	answer.Downvotes = 0
 
-	filter := bson.M{"_id": qnaID}
-
-	// Ensure answers field is an array
-	setIfNull := bson.M{
-		"$set": bson.M{"answers": bson.M{"$ifNull": []interface{}{bson.M{"$type": "array"}, []models.Answer{}}}},
-	}
-
-	// Update MongoDB
-	_, err = qnaCollection.UpdateOne(context.TODO(), filter, setIfNull)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize answers array"})
-		return
-	}
-
-	update := bson.M{"$push": bson.M{"answers": answer}}
+	answer.QuestionID = qnaID
+	answer.CommentCount = 0
+	answer.ModerationStatus = ""
+	answer.EditedAt = nil
 
-	// Perform update
-	result, err := qnaCollection.UpdateOne(context.TODO(), filter, update)
+	// Count the answer on the question first, so answers never land on a missing
+	// question. Questions closed as duplicates take no new answers.
+	update := bson.M{"$inc": bson.M{"answerCount": 1}, "$set": bson.M{"lastActivityAt": answer.CreatedAt}}
+	result, err := qnaCollection.UpdateOne(context.TODO(), bson.M{"_id": qnaID, "duplicateOf": bson.M{"$exists": false}}, update)
 	if err != nil {
 		fmt.Println("MongoDB UpdateOne Error:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post answer"})
 		return
 	}
 
-	// Debug: Check MongoDB update result
-	fmt.Println("MongoDB Update Result:", result)
-
 	if result.MatchedCount == 0 {
+		if n, _ := qnaCollection.CountDocuments(context.TODO(), bson.M{"_id": qnaID}); n > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "This question was closed as a duplicate, answer the original instead"})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Answer posted successfully"})
+	if _, err := getAnswerCollection().InsertOne(context.TODO(), answer); err != nil {
+		_, _ = qnaCollection.UpdateOne(context.TODO(), bson.M{"_id": qnaID}, bson.M{"$inc": bson.M{"answerCount": -1}})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post answer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Answer posted successfully", "answer": answer})
 }
 
 // Get All QnA (Paginated)
@@ -155,26 +188,78 @@ func GetPaginatedQnA(c *gin.Context) {
 	if page < 1 {
 		page = 1
 	}
-	if limit < 1 {
+	if limit < 1 || limit > 100 {
 		limit = 10
 	}
 
-	skip := int64((page - 1) * limit)
-	var qnaList []models.Qna
+	// ?sort=new|top|hot|unanswered|active|popular, newest first by default
+	sortMode := c.DefaultQuery("sort", "new")
+	sortField, ok := feedSortFields[sortMode]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, use new, top, hot, unanswered, active or popular"})
+		return
+	}
 
-	findOptions := options.Find()
-	findOptions.SetSkip(skip)
-	findOptions.SetLimit(int64(limit))
+	// ?resolved=true|false, or ?unresolved=true
+	filter := bson.M{}
+	if c.Query("resolved") == "true" {
+		filter["resolved"] = true
+	} else if c.Query("resolved") == "false" || c.Query("unresolved") == "true" {
+		filter["resolved"] = bson.M{"$ne": true}
+	}
+
+	// ?tags=a,b matches questions carrying all of the tags
+	if tags := normalizeTags(strings.Split(c.Query("tags"), ",")); len(tags) > 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+	for _, field := range []string{"branch", "semester", "subject"} {
+		if value := strings.TrimSpace(c.Query(field)); value != "" {
+			filter[field] = value
+		}
+	}
+
+	// Hidden and removed questions stay out of the feed
+	filter["moderationStatus"] = bson.M{"$exists": false}
+
+	if sortMode == "unanswered" {
+		filter["answerCount"] = bson.M{"$not": bson.M{"$gt": 0}}
+	}
 
-	cursor, err := qnaCollection.Find(context.TODO(), bson.M{}, findOptions)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
+	total, err := qnaCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch questions"})
 		return
 	}
-	defer cursor.Close(context.TODO())
 
-	for cursor.Next(context.TODO()) {
+	findOptions := options.Find()
+	findOptions.SetSort(primitive.D{{Key: sortField, Value: -1}, {Key: "_id", Value: -1}})
+	findOptions.SetLimit(int64(limit) + 1)
+
+	// A cursor continues after the last question seen; page/skip is kept for older clients
+	pageFilter := filter
+	if encoded := c.Query("cursor"); encoded != "" {
+		after, err := decodeFeedCursor(encoded, sortMode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		pageFilter = bson.M{"$and": []interface{}{filter, after}}
+	} else {
+		findOptions.SetSkip(int64((page - 1) * limit))
+	}
+
+	cursor, err := qnaCollection.Find(ctx, pageFilter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch questions"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	qnaList := []models.Qna{}
+	for cursor.Next(ctx) {
 		var qna models.Qna
 		if err := cursor.Decode(&qna); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding data"})
@@ -183,7 +268,42 @@ func GetPaginatedQnA(c *gin.Context) {
 		qnaList = append(qnaList, qna)
 	}
 
-	c.JSON(http.StatusOK, qnaList)
+	hasMore := len(qnaList) > limit
+	if hasMore {
+		qnaList = qnaList[:limit]
+	}
+
+	nextCursor := ""
+	if hasMore {
+		last := qnaList[len(qnaList)-1]
+		nextCursor = encodeFeedCursor(sortMode, feedSortValue(last, sortMode), last.ID)
+	}
+
+	if err := fillAnswerPreviews(ctx, qnaList); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+
+	// Show signed in users how they voted
+	if userID, ok := currentUserID(c); ok {
+		if err := fillMyVotes(ctx, userID, qnaList); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch votes"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": qnaList,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+			"sort":       sortMode,
+			"hasMore":    hasMore,
+			"nextCursor": nextCursor,
+		},
+	})
 }
 
 // Upvote/Downvote Question
@@ -191,7 +311,7 @@ func VoteQuestion(c *gin.Context) {
 	qnaCollection := getQnaCollection()
 	id := c.Param("id")
 	var request struct {
-		VoteType string `json:"voteType"` // "upvote" or "downvote"
+		VoteType string `json:"voteType"` // "upvote", "downvote" or "none"
 	}
 
 	if err := c.BindJSON(&request); err != nil {
@@ -205,31 +325,64 @@ func VoteQuestion(c *gin.Context) {
 		return
 	}
 
-	update := bson.M{}
-	if request.VoteType == "upvote" {
-		update = bson.M{"$inc": bson.M{"upvotes": 1}}
-	} else if request.VoteType == "downvote" {
-		update = bson.M{"$inc": bson.M{"downvotes": 1}}
-	} else {
+	direction, ok := parseVoteType(request.VoteType)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote type"})
 		return
 	}
 
-	_, err = qnaCollection.UpdateOne(context.TODO(), bson.M{"_id": qnaID}, update)
+	voterID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var question models.Qna
+	if err := qnaCollection.FindOne(ctx, bson.M{"_id": qnaID}).Decode(&question); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	previous, current, err := castVote(ctx, voterID, question.ID, models.VoteTargetQuestion, direction)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Vote recorded successfully"})
+	// Keep the counters in step with the ledger
+	up, down := voteCounterDelta(previous, current)
+	if up != 0 || down != 0 {
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := qnaCollection.FindOneAndUpdate(ctx, bson.M{"_id": qnaID}, questionVoteUpdate(up, down), opts).Decode(&question); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
+			return
+		}
+	}
+
+	if voterID != question.PostedByID {
+		points := votePoints(models.VoteTargetQuestion, current) - votePoints(models.VoteTargetQuestion, previous)
+		err = awardReputation(ctx, question.PostedByID, points, models.ReputationQuestionVoted, question.ID, "")
+		if err != nil {
+			log.Println("⚠️ Failed to award reputation:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Vote recorded successfully",
+		"myVote":    current,
+		"upvotes":   question.Upvotes,
+		"downvotes": question.Downvotes,
+	})
 }
 
 // Upvote/Downvote Answer
 func VoteAnswer(c *gin.Context) {
-	qnaCollection := getQnaCollection()
 	id := c.Param("id")
 	var request struct {
-		VoteType string `json:"voteType"` // "upvote" or "downvote"
+		VoteType string `json:"voteType"` // "upvote", "downvote" or "none"
 	}
 
 	if err := c.BindJSON(&request); err != nil {
@@ -243,44 +396,475 @@ func VoteAnswer(c *gin.Context) {
 		return
 	}
 
-	filter := bson.M{"answers._id": answerID}
-	update := bson.M{"$inc": bson.M{"answers.$.upvotes": 1}}
-	if request.VoteType == "downvote" {
-		update = bson.M{"$inc": bson.M{"answers.$.downvotes": 1}}
+	direction, ok := parseVoteType(request.VoteType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote type"})
+		return
+	}
+
+	voterID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	answers := getAnswerCollection()
+	var answer models.Answer
+	if err := answers.FindOne(ctx, bson.M{"_id": answerID}).Decode(&answer); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
 	}
 
-	_, err = qnaCollection.UpdateOne(context.TODO(), filter, update)
+	previous, current, err := castVote(ctx, voterID, answerID, models.VoteTargetAnswer, direction)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Vote recorded successfully"})
+	// Keep the counters in step with the ledger
+	up, down := voteCounterDelta(previous, current)
+	if up != 0 || down != 0 {
+		update := bson.M{"$inc": bson.M{"upvotes": up, "downvotes": down}}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := answers.FindOneAndUpdate(ctx, bson.M{"_id": answerID}, update, opts).Decode(&answer); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vote"})
+			return
+		}
+	}
+
+	if voterID != answer.PostedByID {
+		points := votePoints(models.VoteTargetAnswer, current) - votePoints(models.VoteTargetAnswer, previous)
+		err = awardReputation(ctx, answer.PostedByID, points, models.ReputationAnswerVoted, answer.ID, "")
+		if err != nil {
+			log.Println("⚠️ Failed to award reputation:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Vote recorded successfully",
+		"myVote":    current,
+		"upvotes":   answer.Upvotes,
+		"downvotes": answer.Downvotes,
+	})
 }
 
-// Report QnA
-func ReportQuestion(c *gin.Context) {
+// AcceptAnswer lets the asker mark one answer as the accepted solution, or switch
+// to another one later. The accepted answer's author earns reputation.
+func AcceptAnswer(c *gin.Context) {
+	qnaCollection := getQnaCollection()
+
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
 
+	var request struct {
+		AnswerID string `json:"answerId"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	answerID, err := primitive.ObjectIDFromHex(request.AnswerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer ID"})
+		return
+	}
+
+	setAcceptedAnswer(c, qnaCollection, qnaID, &answerID)
+}
+
+// UnacceptAnswer clears the accepted answer, marking the question unresolved again
+func UnacceptAnswer(c *gin.Context) {
 	qnaCollection := getQnaCollection()
 
-	id := c.Param("id")
-	var report models.Report
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
 
-	if err := c.BindJSON(&report); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report data"})
+	setAcceptedAnswer(c, qnaCollection, qnaID, nil)
+}
+
+// setAcceptedAnswer swaps the accepted answer of a question (nil clears it)
+// and moves the acceptance reputation from the old answerer to the new one
+func setAcceptedAnswer(c *gin.Context, qnaCollection *mongo.Collection, qnaID primitive.ObjectID, answerID *primitive.ObjectID) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var question models.Qna
+	if err := qnaCollection.FindOne(ctx, bson.M{"_id": qnaID}).Decode(&question); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
 		return
 	}
 
-	qID, _ := primitive.ObjectIDFromHex(id)
-	report.CreatedAt = time.Now()
+	if question.PostedByID.IsZero() || question.PostedByID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the person who asked can accept an answer"})
+		return
+	}
+
+	// Authors of the previously and newly accepted answers
+	var involved []primitive.ObjectID
+	for _, id := range []*primitive.ObjectID{question.AcceptedAnswerID, answerID} {
+		if id != nil {
+			involved = append(involved, *id)
+		}
+	}
+	authors, err := answerAuthors(ctx, qnaID, involved)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load answers"})
+		return
+	}
+
+	if answerID != nil {
+		if _, exists := authors[*answerID]; !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found on this question"})
+			return
+		}
+	}
+
+	previous := question.AcceptedAnswerID
+	if (previous == nil && answerID == nil) || (previous != nil && answerID != nil && *previous == *answerID) {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to change", "acceptedAnswerId": answerID, "resolved": answerID != nil})
+		return
+	}
 
-	update := bson.M{"$push": bson.M{"reports": report}}
-	_, err := qnaCollection.UpdateOne(context.TODO(), bson.M{"_id": qID}, update)
+	// Only swap if nobody changed the accepted answer since we read it
+	filter := bson.M{"_id": qnaID, "acceptedAnswerId": bson.M{"$exists": false}}
+	if previous != nil {
+		filter["acceptedAnswerId"] = *previous
+	}
+	update := bson.M{"$set": bson.M{"resolved": true, "acceptedAnswerId": answerID}}
+	if answerID == nil {
+		update = bson.M{"$set": bson.M{"resolved": false}, "$unset": bson.M{"acceptedAnswerId": ""}}
+	}
 
+	result, err := qnaCollection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report question"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update accepted answer"})
 		return
 	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The accepted answer was changed meanwhile, please reload"})
+		return
+	}
+
+	// Askers do not earn reputation for accepting their own answer
+	if previous != nil && authors[*previous] != userID {
+		if err := awardReputation(ctx, authors[*previous], -pointsAnswerAccepted, models.ReputationAnswerAccepted, *previous, ""); err != nil {
+			log.Println("⚠️ Failed to award reputation:", err)
+		}
+	}
+	if answerID != nil && authors[*answerID] != userID {
+		if err := awardReputation(ctx, authors[*answerID], pointsAnswerAccepted, models.ReputationAnswerAccepted, *answerID, ""); err != nil {
+			log.Println("⚠️ Failed to award reputation:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Accepted answer updated", "acceptedAnswerId": answerID, "resolved": answerID != nil})
+}
+
+// EditQuestion lets the asker change the question text, keeping the old text as a revision
+func EditQuestion(c *gin.Context) {
+	qnaCollection := getQnaCollection()
+
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	var request struct {
+		Question string `json:"question"`
+	}
+	if err := c.BindJSON(&request); err != nil || strings.TrimSpace(request.Question) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Question text is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var question models.Qna
+	if err := qnaCollection.FindOne(ctx, bson.M{"_id": qnaID}).Decode(&question); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !isOwner(c, question.PostedByID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own questions"})
+		return
+	}
+
+	if request.Question == question.Question {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to change"})
+		return
+	}
+
+	revisionID, err := saveRevision(ctx, c, qnaID, qnaID, models.VoteTargetQuestion, question.Question, models.RevisionEdited)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+		return
+	}
+
+	// Only apply the edit on top of the text we read, so no version goes unrecorded
+	now := time.Now()
+	result, err := qnaCollection.UpdateOne(ctx,
+		bson.M{"_id": qnaID, "question": question.Question},
+		bson.M{"$set": bson.M{"question": request.Question, "editedAt": now, "lastActivityAt": now}},
+	)
+	if err != nil {
+		discardRevisions(ctx, revisionID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update question"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		discardRevisions(ctx, revisionID)
+		c.JSON(http.StatusConflict, gin.H{"error": "The question was changed meanwhile, please reload"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question updated successfully"})
+}
+
+// DeleteQuestion removes a question with its answers; the asker or a moderator may delete it
+func DeleteQuestion(c *gin.Context) {
+	qnaCollection := getQnaCollection()
+
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var question models.Qna
+	if err := qnaCollection.FindOne(ctx, bson.M{"_id": qnaID}).Decode(&question); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if !canModify(c, question.PostedByID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own questions"})
+		return
+	}
+
+	answers := getAnswerCollection()
+	var removed []models.Answer
+	cursor, err := answers.Find(ctx, bson.M{"questionId": qnaID})
+	if err == nil {
+		err = cursor.All(ctx, &removed)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load answers"})
+		return
+	}
+
+	// Keep the question and every answer before anything is removed
+	revisionID, err := saveRevision(ctx, c, qnaID, qnaID, models.VoteTargetQuestion, question.Question, models.RevisionDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+		return
+	}
+	revisionIDs := []primitive.ObjectID{revisionID}
+	targetIDs := []primitive.ObjectID{qnaID}
+	for _, answer := range removed {
+		revisionID, err := saveRevision(ctx, c, answer.ID, qnaID, models.VoteTargetAnswer, answer.Text, models.RevisionDeleted)
+		if err != nil {
+			discardRevisions(ctx, revisionIDs...)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+			return
+		}
+		revisionIDs = append(revisionIDs, revisionID)
+		targetIDs = append(targetIDs, answer.ID)
+	}
+
+	result, err := qnaCollection.DeleteOne(ctx, bson.M{"_id": qnaID})
+	if err != nil {
+		discardRevisions(ctx, revisionIDs...)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete question"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		discardRevisions(ctx, revisionIDs...)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if _, err := answers.DeleteMany(ctx, bson.M{"questionId": qnaID}); err != nil {
+		log.Println("⚠️ Failed to delete answers:", err)
+	}
+
+	if _, err := getVoteCollection().DeleteMany(ctx, bson.M{"targetId": bson.M{"$in": targetIDs}}); err != nil {
+		log.Println("⚠️ Failed to delete votes:", err)
+	}
+	if err := revokeReputation(ctx, targetIDs...); err != nil {
+		log.Println("⚠️ Failed to take back reputation:", err)
+	}
+	deleteComments(ctx, bson.M{"questionId": qnaID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question deleted successfully"})
+}
+
+// findAnswer returns the answer and the question it belongs to
+func findAnswer(ctx context.Context, qnaCollection *mongo.Collection, answerID primitive.ObjectID) (*models.Qna, *models.Answer, error) {
+	var answer models.Answer
+	if err := getAnswerCollection().FindOne(ctx, bson.M{"_id": answerID}).Decode(&answer); err != nil {
+		return nil, nil, err
+	}
+
+	var question models.Qna
+	if err := qnaCollection.FindOne(ctx, bson.M{"_id": answer.QuestionID}).Decode(&question); err != nil {
+		return nil, nil, err
+	}
+
+	return &question, &answer, nil
+}
+
+// EditAnswer lets the author change an answer, keeping the old text as a revision
+func EditAnswer(c *gin.Context) {
+	qnaCollection := getQnaCollection()
+
+	answerID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer ID"})
+		return
+	}
+
+	var request struct {
+		Text string `json:"text"`
+	}
+	if err := c.BindJSON(&request); err != nil || strings.TrimSpace(request.Text) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Answer text is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	question, answer, err := findAnswer(ctx, qnaCollection, answerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
+	}
+
+	if !isOwner(c, answer.PostedByID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own answers"})
+		return
+	}
+
+	if request.Text == answer.Text {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to change"})
+		return
+	}
+
+	revisionID, err := saveRevision(ctx, c, answerID, question.ID, models.VoteTargetAnswer, answer.Text, models.RevisionEdited)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+		return
+	}
+
+	now := time.Now()
+	result, err := getAnswerCollection().UpdateOne(ctx,
+		bson.M{"_id": answerID, "text": answer.Text},
+		bson.M{"$set": bson.M{"text": request.Text, "editedAt": now}},
+	)
+	if err != nil {
+		discardRevisions(ctx, revisionID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update answer"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		discardRevisions(ctx, revisionID)
+		c.JSON(http.StatusConflict, gin.H{"error": "The answer was changed meanwhile, please reload"})
+		return
+	}
+
+	if _, err := qnaCollection.UpdateOne(ctx, bson.M{"_id": question.ID}, bson.M{"$set": bson.M{"lastActivityAt": now}}); err != nil {
+		log.Println("⚠️ Failed to update question activity:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Answer updated successfully"})
+}
+
+// DeleteAnswer removes an answer; its author or a moderator may delete it
+func DeleteAnswer(c *gin.Context) {
+	qnaCollection := getQnaCollection()
+
+	answerID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	question, answer, err := findAnswer(ctx, qnaCollection, answerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
+	}
+
+	if !canModify(c, answer.PostedByID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own answers"})
+		return
+	}
+
+	revisionID, err := saveRevision(ctx, c, answerID, question.ID, models.VoteTargetAnswer, answer.Text, models.RevisionDeleted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+		return
+	}
+
+	result, err := getAnswerCollection().DeleteOne(ctx, bson.M{"_id": answerID})
+	if err != nil {
+		discardRevisions(ctx, revisionID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete answer"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		discardRevisions(ctx, revisionID)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
+	}
+
+	if _, err := qnaCollection.UpdateOne(ctx, bson.M{"_id": question.ID}, bson.M{"$inc": bson.M{"answerCount": -1}}); err != nil {
+		log.Println("⚠️ Failed to update answer count:", err)
+	}
+
+	// A deleted answer can no longer resolve the question
+	_, err = qnaCollection.UpdateOne(ctx,
+		bson.M{"_id": question.ID, "acceptedAnswerId": answerID},
+		bson.M{"$set": bson.M{"resolved": false}, "$unset": bson.M{"acceptedAnswerId": ""}},
+	)
+	if err != nil {
+		log.Println("⚠️ Failed to clear accepted answer:", err)
+	}
+
+	// nor keep the reputation its votes and acceptance earned
+	if err := revokeReputation(ctx, answerID); err != nil {
+		log.Println("⚠️ Failed to take back reputation:", err)
+	}
+
+	if _, err := getVoteCollection().DeleteMany(ctx, bson.M{"targetId": answerID}); err != nil {
+		log.Println("⚠️ Failed to delete votes:", err)
+	}
+	deleteComments(ctx, bson.M{"answerId": answerID})
 
-	c.JSON(http.StatusOK, gin.H{"message": "Report submitted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Answer deleted successfully"})
 }
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// A viewer counts once per question within this window
+	viewWindow = 24 * time.Hour
+
+	detailAnswerLimit = 50
+	relatedLimit      = 5
+)
+
+var (
+	qnaViewCollection *mongo.Collection
+	qnaViewOnce       sync.Once
+)
+
+func getQnaViewCollection() *mongo.Collection {
+	qnaViewOnce.Do(func() {
+		qnaViewCollection = config.GetCollection("qna_views")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = qnaViewCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+	})
+	return qnaViewCollection
+}
+
+// recordView counts a view unless the same user, or the same IP for anonymous
+// visitors, already viewed the question within viewWindow. IPs are stored as keyed hashes.
+func recordView(ctx context.Context, c *gin.Context, qnaID primitive.ObjectID) bool {
+	viewer := "ip:" + utils.HashClientIP(c.ClientIP())
+	if userID, ok := currentUserID(c); ok {
+		viewer = "user:" + userID.Hex()
+	}
+
+	now := time.Now()
+	view := bson.M{
+		"_id":        qnaID.Hex() + ":" + viewer,
+		"questionId": qnaID,
+		"viewedAt":   now,
+		"expiresAt":  now.Add(viewWindow),
+	}
+	if _, err := getQnaViewCollection().InsertOne(ctx, view); err != nil {
+		if !mongo.IsDuplicateKeyError(err) {
+			log.Println("⚠️ Failed to record view:", err)
+		}
+		return false
+	}
+
+	_, err := getQnaCollection().UpdateOne(ctx, bson.M{"_id": qnaID}, bson.M{"$inc": bson.M{"viewCount": 1}})
+	if err != nil {
+		log.Println("⚠️ Failed to count view:", err)
+		return false
+	}
+	return true
+}
+
+// questionAnswers returns the question's answers with the accepted one first,
+// then by votes and age, and whether there are more than detailAnswerLimit
+func questionAnswers(ctx context.Context, c *gin.Context, question *models.Qna) ([]models.Answer, bool, error) {
+	answers := getAnswerCollection()
+	result := []models.Answer{}
+
+	// Duplicates and their original share their answers
+	group, err := duplicateGroup(ctx, question)
+	if err != nil {
+		return nil, false, err
+	}
+
+	filter := bson.M{"questionId": bson.M{"$in": group}}
+	if !isModerator(c) {
+		filter["moderationStatus"] = bson.M{"$exists": false}
+	}
+
+	if question.AcceptedAnswerID != nil {
+		var accepted models.Answer
+		err := answers.FindOne(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": *question.AcceptedAnswerID}}}).Decode(&accepted)
+		if err == nil {
+			result = append(result, accepted)
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, err
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$ne": *question.AcceptedAnswerID}}}}
+	}
+
+	opts := options.Find().SetSort(answerSorts["top"]).SetLimit(int64(detailAnswerLimit - len(result) + 1))
+	cursor, err := answers.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var rest []models.Answer
+	if err := cursor.All(ctx, &rest); err != nil {
+		return nil, false, err
+	}
+	result = append(result, rest...)
+
+	hasMore := len(result) > detailAnswerLimit
+	if hasMore {
+		result = result[:detailAnswerLimit]
+	}
+	return result, hasMore, nil
+}
+
+// relatedQuestions finds other visible questions sharing a tag or the subject
+func relatedQuestions(ctx context.Context, question *models.Qna) ([]models.Qna, error) {
+	var match bson.A
+	if len(question.Tags) > 0 {
+		match = append(match, bson.M{"tags": bson.M{"$in": question.Tags}})
+	}
+	if question.Subject != "" {
+		match = append(match, bson.M{"subject": question.Subject})
+	}
+	if len(match) == 0 {
+		return []models.Qna{}, nil
+	}
+
+	filter := bson.M{
+		"_id":              bson.M{"$ne": question.ID},
+		"moderationStatus": bson.M{"$exists": false},
+		"duplicateOf":      bson.M{"$exists": false},
+		"$or":              match,
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "score", Value: -1}, {Key: "viewCount", Value: -1}}).
+		SetLimit(relatedLimit).
+		SetProjection(bson.M{"question": 1, "tags": 1, "answerCount": 1, "resolved": 1, "score": 1, "createdAt": 1})
+
+	cursor, err := getQnaCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	related := []models.Qna{}
+	if err := cursor.All(ctx, &related); err != nil {
+		return nil, err
+	}
+	return related, nil
+}
+
+// GetQuestion returns one question with its answers, related questions and the
+// caller's votes, and counts the view
+func GetQuestion(c *gin.Context) {
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var question models.Qna
+	if err := getQnaCollection().FindOne(ctx, bson.M{"_id": qnaID}).Decode(&question); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	// Moderated questions are only visible to their asker and moderators
+	if question.ModerationStatus != "" && !canModify(c, question.PostedByID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	if recordView(ctx, c, qnaID) {
+		question.ViewCount++
+	}
+
+	answers, hasMoreAnswers, err := questionAnswers(ctx, c, &question)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+	question.Answers = answers
+
+	original, err := duplicateOriginal(ctx, &question)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch original question"})
+		return
+	}
+
+	related, err := relatedQuestions(ctx, &question)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related questions"})
+		return
+	}
+
+	// Show signed in users how they voted
+	if userID, ok := currentUserID(c); ok {
+		questions := []models.Qna{question}
+		if err := fillMyVotes(ctx, userID, questions); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch votes"})
+			return
+		}
+		question = questions[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question":       question,
+		"hasMoreAnswers": hasMoreAnswers,
+		"related":        related,
+		"duplicateOf":    original, // readers should be sent here when set
+	})
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	duplicateCandidates = 20   // text search hits to compare
+	duplicateSuggestion = 5    // suggestions returned
+	duplicateThreshold  = 0.35 // minimum word overlap to suggest
+)
+
+// similarQuestion is an existing question that may answer a new one
+type similarQuestion struct {
+	ID          primitive.ObjectID `json:"id"`
+	Question    string             `json:"question"`
+	AnswerCount int                `json:"answerCount"`
+	Resolved    bool               `json:"resolved"`
+	Similarity  float64            `json:"similarity"`
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "was": true, "of": true,
+	"to": true, "in": true, "on": true, "for": true, "and": true, "or": true, "any": true,
+	"anyone": true, "does": true, "do": true, "can": true, "have": true, "has": true,
+	"i": true, "me": true, "my": true, "we": true, "you": true, "it": true, "this": true,
+	"that": true, "what": true, "how": true, "where": true, "which": true, "please": true,
+	"with": true, "from": true, "get": true, "there": true,
+}
+
+// questionWords reduces a question to its significant words, with plurals folded
+func questionWords(text string) map[string]bool {
+	words := map[string]bool{}
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range fields {
+		if len(w) < 2 || stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = strings.TrimSuffix(w, "s")
+		}
+		words[w] = true
+	}
+	return words
+}
+
+// wordSimilarity is the Jaccard index of two word sets
+func wordSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// similarQuestions finds visible questions worded like text, most similar first.
+// Candidates come from the text index and are ranked by word overlap.
+func similarQuestions(ctx context.Context, text string, exclude primitive.ObjectID) ([]similarQuestion, error) {
+	result := []similarQuestion{}
+	words := questionWords(text)
+	if len(words) == 0 {
+		return result, nil
+	}
+
+	filter := bson.M{
+		"$text":            bson.M{"$search": text},
+		"_id":              bson.M{"$ne": exclude},
+		"moderationStatus": bson.M{"$exists": false},
+		"duplicateOf":      bson.M{"$exists": false},
+	}
+	opts := options.Find().
+		SetProjection(bson.M{"question": 1, "answerCount": 1, "resolved": 1}).
+		SetSort(bson.M{"relevance": bson.M{"$meta": "textScore"}}).
+		SetLimit(duplicateCandidates)
+
+	cursor, err := getQnaCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []models.Qna
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, err
+	}
+
+	for _, q := range candidates {
+		similarity := wordSimilarity(words, questionWords(q.Question))
+		if similarity < duplicateThreshold {
+			continue
+		}
+		result = append(result, similarQuestion{
+			ID:          q.ID,
+			Question:    q.Question,
+			AnswerCount: q.AnswerCount,
+			Resolved:    q.Resolved,
+			Similarity:  similarity,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Similarity > result[j].Similarity })
+	if len(result) > duplicateSuggestion {
+		result = result[:duplicateSuggestion]
+	}
+	return result, nil
+}
+
+// SuggestDuplicates lets the frontend show likely duplicates while a question is being typed
+func SuggestDuplicates(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	duplicates, err := similarQuestions(ctx, text, primitive.NilObjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicates)
+}
+
+// duplicateGroup returns the IDs of the question's canonical question and every
+// question closed as its duplicate, whose answers are shown together
+func duplicateGroup(ctx context.Context, question *models.Qna) ([]primitive.ObjectID, error) {
+	canonical := question.ID
+	if question.DuplicateOfID != nil {
+		canonical = *question.DuplicateOfID
+	}
+
+	ids, err := getQnaCollection().Distinct(ctx, "_id", bson.M{"duplicateOf": canonical})
+	if err != nil {
+		return nil, err
+	}
+
+	group := []primitive.ObjectID{canonical}
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			group = append(group, oid)
+		}
+	}
+	return group, nil
+}
+
+// CloseAsDuplicate closes a question as a duplicate of another. Readers are sent to
+// the original, and both questions show each other's answers.
+func CloseAsDuplicate(c *gin.Context) {
+	qnaCollection := getQnaCollection()
+
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	var request struct {
+		DuplicateOf string `json:"duplicateOf"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	originalID, err := primitive.ObjectIDFromHex(request.DuplicateOf)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid original question ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var original models.Qna
+	if err := qnaCollection.FindOne(ctx, bson.M{"_id": originalID}).Decode(&original); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Original question not found"})
+		return
+	}
+
+	// Always point at the canonical question, never at another duplicate
+	if original.DuplicateOfID != nil {
+		originalID = *original.DuplicateOfID
+	}
+	if originalID == qnaID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A question cannot be a duplicate of itself"})
+		return
+	}
+
+	moderatorID, _ := currentUserID(c)
+	now := time.Now()
+	result, err := qnaCollection.UpdateOne(ctx, bson.M{"_id": qnaID}, bson.M{"$set": bson.M{
+		"duplicateOf": originalID,
+		"closedBy":    moderatorID,
+		"closedAt":    now,
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close question"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	// Questions that were duplicates of this one now belong to the original
+	_, err = qnaCollection.UpdateMany(ctx, bson.M{"duplicateOf": qnaID}, bson.M{"$set": bson.M{"duplicateOf": originalID}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close question"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question closed as duplicate", "duplicateOf": originalID})
+}
+
+// ReopenQuestion undoes closing a question as a duplicate
+func ReopenQuestion(c *gin.Context) {
+	qnaID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := getQnaCollection().UpdateOne(ctx,
+		bson.M{"_id": qnaID},
+		bson.M{"$unset": bson.M{"duplicateOf": "", "closedBy": "", "closedAt": ""}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reopen question"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question reopened"})
+}
+
+// duplicateOriginal loads the question a duplicate was closed in favour of
+func duplicateOriginal(ctx context.Context, question *models.Qna) (*models.Qna, error) {
+	if question.DuplicateOfID == nil {
+		return nil, nil
+	}
+
+	var original models.Qna
+	opts := options.FindOne().SetProjection(bson.M{"question": 1, "answerCount": 1, "resolved": 1, "createdAt": 1})
+	err := getQnaCollection().FindOne(ctx, bson.M{"_id": *question.DuplicateOfID}, opts).Decode(&original)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &original, nil
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Hot scores grow by one every 12.5 hours, so a question needs ten times the
+// votes to stay level with one asked 12.5 hours later
+const hotScoreDecay = 45000 * time.Second
+
+var hotScoreEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// feedSortFields maps each ?sort= mode to the field it orders by, descending
+var feedSortFields = map[string]string{
+	"new":        "createdAt",
+	"unanswered": "createdAt",
+	"top":        "score",
+	"hot":        "hotScore",
+	"active":     "lastActivityAt",
+	"popular":    "viewCount",
+}
+
+// hotScore ranks a question by its net votes on a log scale plus its age
+func hotScore(score int, createdAt time.Time) float64 {
+	sign := 0.0
+	if score > 0 {
+		sign = 1
+	} else if score < 0 {
+		sign = -1
+	}
+	magnitude := math.Log10(math.Max(math.Abs(float64(score)), 1))
+	return sign*magnitude + float64(createdAt.Sub(hotScoreEpoch))/float64(hotScoreDecay)
+}
+
+// rankStages recomputes score and hotScore inside an update pipeline, mirroring hotScore
+func rankStages() []bson.M {
+	return []bson.M{
+		{"$set": bson.M{"score": bson.M{"$subtract": bson.A{
+			bson.M{"$ifNull": bson.A{"$upvotes", 0}},
+			bson.M{"$ifNull": bson.A{"$downvotes", 0}},
+		}}}},
+		{"$set": bson.M{"hotScore": bson.M{"$add": bson.A{
+			bson.M{"$multiply": bson.A{
+				bson.M{"$cmp": bson.A{"$score", 0}},
+				bson.M{"$log10": bson.M{"$max": bson.A{bson.M{"$abs": "$score"}, 1}}},
+			}},
+			bson.M{"$divide": bson.A{
+				bson.M{"$subtract": bson.A{"$createdAt", hotScoreEpoch}},
+				hotScoreDecay.Milliseconds(),
+			}},
+		}}}},
+	}
+}
+
+// questionVoteUpdate applies vote counter deltas and refreshes the ranking in one atomic update
+func questionVoteUpdate(up, down int) []bson.M {
+	counters := bson.M{"$set": bson.M{
+		"upvotes":   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$upvotes", 0}}, up}},
+		"downvotes": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$downvotes", 0}}, down}},
+	}}
+	return append([]bson.M{counters}, rankStages()...)
+}
+
+// backfillQnaRanking fills score, hotScore, lastActivityAt and the counters on
+// questions posted before the feed could be sorted. Every sort field must exist,
+// or keyset pagination skips the questions that lack it.
+func backfillQnaRanking(collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	update := append(rankStages(), bson.M{"$set": bson.M{
+		"lastActivityAt": bson.M{"$ifNull": bson.A{"$lastActivityAt", "$createdAt"}},
+		"viewCount":      bson.M{"$ifNull": bson.A{"$viewCount", 0}},
+		"answerCount":    bson.M{"$ifNull": bson.A{"$answerCount", 0}},
+	}})
+	filter := bson.M{"$or": bson.A{
+		bson.M{"hotScore": bson.M{"$exists": false}},
+		bson.M{"lastActivityAt": bson.M{"$exists": false}},
+		bson.M{"viewCount": bson.M{"$exists": false}},
+		bson.M{"answerCount": bson.M{"$exists": false}},
+	}}
+
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		log.Println("⚠️ Failed to backfill question ranking:", err)
+	}
+}
+
+// feedCursor marks the last question a client has seen, so the next page starts
+// right after it even when new questions arrive in between
+type feedCursor struct {
+	Sort  string             `json:"s"`
+	Value interface{}        `json:"v"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+var errInvalidFeedCursor = errors.New("invalid cursor")
+
+// encodeFeedCursor builds the cursor for the page ending at the given question
+func encodeFeedCursor(sort string, value interface{}, id primitive.ObjectID) string {
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC().Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(feedCursor{Sort: sort, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeFeedCursor parses a cursor and returns the filter selecting the questions after it
+func decodeFeedCursor(encoded, sort string) (bson.M, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidFeedCursor
+	}
+
+	var cursor feedCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Sort != sort || cursor.ID.IsZero() {
+		return nil, errInvalidFeedCursor
+	}
+
+	field := feedSortFields[sort]
+	var value interface{}
+	switch v := cursor.Value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, errInvalidFeedCursor
+		}
+		value = t
+	case float64:
+		value = v
+	default:
+		return nil, errInvalidFeedCursor
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{"$lt": value}},
+		bson.M{field: value, "_id": bson.M{"$lt": cursor.ID}},
+	}}, nil
+}
+
+// feedSortValue returns the value a question is ordered by, used to build its cursor
+func feedSortValue(q models.Qna, sort string) interface{} {
+	switch feedSortFields[sort] {
+	case "score":
+		return float64(q.Score)
+	case "hotScore":
+		return q.HotScore
+	case "lastActivityAt":
+		return q.LastActivityAt
+	case "viewCount":
+		return float64(q.ViewCount)
+	}
+	return q.CreatedAt
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxQuestionTags = 5
+	maxTagLength    = 30
+)
+
+// createQnaIndexes backs the filters and sort modes of the question listing
+func createQnaIndexes(collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, _ = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "branch", Value: 1}, {Key: "semester", Value: 1}, {Key: "subject", Value: 1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "hotScore", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "lastActivityAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "viewCount", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "duplicateOf", Value: 1}}},
+		{
+			Keys: bson.D{{Key: "question", Value: "text"}, {Key: "tags", Value: "text"}},
+			Options: options.Index().SetName("qna_text").
+				SetWeights(bson.D{{Key: "question", Value: 3}, {Key: "tags", Value: 1}}),
+		},
+	})
+}
+
+// normalizeTags lowercases tags, joins words with dashes and drops empty or repeated ones,
+// so "Data Structures" and "data-structures" are the same tag
+func normalizeTags(tags []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if runes := []rune(tag); len(runes) > maxTagLength {
+			tag = string(runes[:maxTagLength])
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+// GetQnaTags suggests existing tags starting with ?prefix=, most used first
+func GetQnaTags(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	match := bson.M{}
+	if prefix := normalizeTags([]string{c.Query("prefix")}); len(prefix) > 0 {
+		match["tags"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix[0])}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "tag": "$_id", "count": 1}}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := getQnaCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	type tagCount struct {
+		Tag   string `bson:"tag" json:"tag"`
+		Count int    `bson:"count" json:"count"`
+	}
+	tags := []tagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Points awarded for each kind of contribution
+const (
+	pointsDocumentUploaded = 10
+	pointsBlogPublished    = 15
+	pointsQuestionUpvote   = 5
+	pointsQuestionDownvote = -2
+	pointsAnswerUpvote     = 10
+	pointsAnswerDownvote   = -2
+	pointsAnswerAccepted   = 15
+)
+
+var (
+	reputationCollection *mongo.Collection
+	reputationOnce       sync.Once
+)
+
+func getReputationCollection() *mongo.Collection {
+	reputationOnce.Do(func() {
+		reputationCollection = config.GetCollection("reputation_events")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = reputationCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "key", Value: 1}},
+				Options: options.Index().SetUnique(true).
+					SetPartialFilterExpression(bson.M{"key": bson.M{"$type": "string"}}),
+			},
+			{Keys: bson.D{{Key: "createdAt", Value: -1}, {Key: "userId", Value: 1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		})
+	})
+	return reputationCollection
+}
+
+// awardReputation records points in the ledger and adds them to the user's total.
+// Awards with a key are only applied once; repeating them is a no-op.
+func awardReputation(ctx context.Context, userID primitive.ObjectID, points int, reason string, sourceID primitive.ObjectID, key string) error {
+	if userID.IsZero() || points == 0 {
+		return nil
+	}
+
+	event := models.ReputationEvent{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Points:    points,
+		Reason:    reason,
+		SourceID:  sourceID,
+		Key:       key,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := getReputationCollection().InsertOne(ctx, event); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return err
+	}
+
+	// $inc is atomic, so concurrent awards never lose points
+	_, err := getUserCollection().UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"reputation": points}},
+	)
+	return err
+}
+
+// revokeReputation takes back the points deleted contributions earned. For each
+// user and reason the net points recorded for a source are reversed once, in
+// an event keyed "<reason>:<source>:<user>:revoked".
+func revokeReputation(ctx context.Context, sourceIDs ...primitive.ObjectID) error {
+	if len(sourceIDs) == 0 {
+		return nil
+	}
+
+	cursor, err := getReputationCollection().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"sourceId": bson.M{"$in": sourceIDs}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"sourceId": "$sourceId", "userId": "$userId", "reason": "$reason"},
+			"points": bson.M{"$sum": "$points"},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var totals []struct {
+		ID struct {
+			SourceID primitive.ObjectID `bson:"sourceId"`
+			UserID   primitive.ObjectID `bson:"userId"`
+			Reason   string             `bson:"reason"`
+		} `bson:"_id"`
+		Points int `bson:"points"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return err
+	}
+
+	for _, total := range totals {
+		key := fmt.Sprintf("%s:%s:%s:revoked", total.ID.Reason, total.ID.SourceID.Hex(), total.ID.UserID.Hex())
+		if err := awardReputation(ctx, total.ID.UserID, -total.Points, total.ID.Reason, total.ID.SourceID, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// leaderboardSince returns the start of a leaderboard window, or zero for all-time
+func leaderboardSince(window string, now time.Time) (time.Time, bool) {
+	switch window {
+	case "", "all", "alltime", "all-time":
+		return time.Time{}, true
+	case "week", "weekly":
+		return now.AddDate(0, 0, -7), true
+	case "month", "monthly":
+		return now.AddDate(0, -1, 0), true
+	}
+	return time.Time{}, false
+}
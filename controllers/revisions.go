@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	revisionCollection *mongo.Collection
+	revisionOnce       sync.Once
+)
+
+func getRevisionCollection() *mongo.Collection {
+	revisionOnce.Do(func() {
+		revisionCollection = config.GetCollection("revisions")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = revisionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "targetId", Value: 1}, {Key: "createdAt", Value: -1}}},
+			{Keys: bson.D{{Key: "questionId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		})
+	})
+	return revisionCollection
+}
+
+// saveRevision stores the text a question or answer has before the caller changes it.
+// It runs before the change, so callers give up when it fails and no version is
+// lost; if the change then does not happen, they discard the revision again.
+func saveRevision(ctx context.Context, c *gin.Context, targetID, questionID primitive.ObjectID, targetType, text, action string) (primitive.ObjectID, error) {
+	editorID, _ := currentUserID(c)
+
+	revision := models.Revision{
+		ID:         primitive.NewObjectID(),
+		TargetID:   targetID,
+		TargetType: targetType,
+		QuestionID: questionID,
+		Text:       text,
+		Action:     action,
+		EditedBy:   c.GetString("fullName"),
+		EditedByID: editorID,
+		CreatedAt:  time.Now(),
+	}
+
+	if _, err := getRevisionCollection().InsertOne(ctx, revision); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return revision.ID, nil
+}
+
+// discardRevisions removes revisions saved for a change that did not go through
+func discardRevisions(ctx context.Context, ids ...primitive.ObjectID) {
+	if _, err := getRevisionCollection().DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		log.Println("⚠️ Failed to discard revision:", err)
+	}
+}
+
+// GetRevisions lists the earlier versions of a question or answer, newest first.
+// For a question it also includes the revisions of its answers.
+func GetRevisions(c *gin.Context) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{{"targetId": targetID}, {"questionId": targetID}}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := getRevisionCollection().Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	revisions := []models.Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding data"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
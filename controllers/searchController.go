@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	snippetRadius  = 80 // characters of context on each side of the first match
+	maxSearchPages = 10
+
+	// How much of a long field is loaded to cut its snippet from. A match
+	// further in shows the start of the field instead.
+	searchTextLimit = 2000
+)
+
+// Result types, also accepted by ?type= (questions include their answers)
+const (
+	searchDocuments = "documents"
+	searchQuestions = "questions"
+	searchBlogs     = "blogs"
+)
+
+// searchResult is one ranked hit. Answers are returned with the question they belong to.
+type searchResult struct {
+	Type       string              `json:"type"` // document, question, answer or blog
+	ID         primitive.ObjectID  `json:"id"`
+	QuestionID *primitive.ObjectID `json:"questionId,omitempty"`
+	Title      string              `json:"title"`
+	Snippet    string              `json:"snippet"` // HTML escaped, matches wrapped in <mark>
+	Score      float64             `json:"score"`
+	CreatedAt  time.Time           `json:"createdAt"`
+}
+
+// Index errors meaning an index with the same name or keys but a different
+// definition already exists
+const (
+	indexOptionsConflict  = 85
+	indexKeySpecsConflict = 86
+)
+
+// ensureTextIndex creates a weighted text index; a collection can only have one
+func ensureTextIndex(ctx context.Context, collection *mongo.Collection, name string, weights bson.D) {
+	keys := bson.D{}
+	for _, field := range weights {
+		keys = append(keys, bson.E{Key: field.Key, Value: "text"})
+	}
+
+	index := mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetName(name).SetWeights(weights),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, index)
+
+	// Replace an older definition of the index when its fields changed
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == indexOptionsConflict || cmdErr.Code == indexKeySpecsConflict) {
+		if _, err = collection.Indexes().DropOne(ctx, name); err == nil {
+			_, err = collection.Indexes().CreateOne(ctx, index)
+		}
+	}
+	if err != nil {
+		log.Println("⚠️ Failed to create text index", name+":", err)
+	}
+}
+
+// EnsureSearchIndexes creates the text indexes the search endpoint relies on.
+// The question index is created with the other qna indexes. Building them can
+// take a while on large collections, so this runs at startup.
+func EnsureSearchIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	getQnaCollection()
+	ensureTextIndex(ctx, getDocumentCollection(), "documents_text",
+		bson.D{{Key: "subject", Value: 3}, {Key: "fileName", Value: 2}, {Key: "content", Value: 1}, {Key: "text", Value: 1}})
+	ensureTextIndex(ctx, getAnswerCollection(), "answers_text",
+		bson.D{{Key: "text", Value: 1}})
+	ensureTextIndex(ctx, GetBlogCollection(), "blogs_text",
+		bson.D{{Key: "title", Value: 3}, {Key: "content", Value: 1}})
+}
+
+// searchTerms returns a pattern matching any of the query's words, for highlighting
+func searchTerms(query string) *regexp.Regexp {
+	var terms []string
+	for _, word := range strings.Fields(strings.Trim(query, `"`)) {
+		word = strings.Trim(word, `"-`)
+		if utf8.RuneCountInString(word) > 1 {
+			terms = append(terms, regexp.QuoteMeta(word))
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+// highlight cuts a snippet around the first match in text and marks every match in it
+func highlight(text string, terms *regexp.Regexp) string {
+	runes := []rune(text)
+	start, end := 0, len(runes)
+
+	if terms != nil {
+		if loc := terms.FindStringIndex(text); loc != nil {
+			at := utf8.RuneCountInString(text[:loc[0]])
+			start = max(at-snippetRadius, 0)
+			end = min(at+snippetRadius, len(runes))
+		}
+	}
+	if start == 0 && end > 2*snippetRadius {
+		end = 2 * snippetRadius
+	}
+
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+
+	if terms == nil {
+		return html.EscapeString(snippet)
+	}
+
+	// Escape around the matches rather than after marking them, so markup in
+	// the text can't break the highlighting
+	var b strings.Builder
+	last := 0
+	for _, loc := range terms.FindAllStringIndex(snippet, -1) {
+		b.WriteString(html.EscapeString(snippet[last:loc[0]]))
+		b.WriteString("<mark>" + html.EscapeString(snippet[loc[0]:loc[1]]) + "</mark>")
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(snippet[last:]))
+	return b.String()
+}
+
+// textSearch runs a $text query and decodes the hits, best first, with their
+// score. Only the projected fields are loaded.
+func textSearch(ctx context.Context, collection *mongo.Collection, query string, extra bson.M, fields bson.M, limit int, out interface{}) error {
+	filter := bson.M{"$text": bson.M{"$search": query}}
+	for k, v := range extra {
+		filter[k] = v
+	}
+
+	projection := bson.M{"textScore": bson.M{"$meta": "textScore"}}
+	for k, v := range fields {
+		projection[k] = v
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.M{"textScore": bson.M{"$meta": "textScore"}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: projection}},
+	})
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, out)
+}
+
+// trimmedField projects the start of a long text field, cut by the server
+func trimmedField(name string) bson.M {
+	return bson.M{"$substrCP": bson.A{bson.M{"$ifNull": bson.A{"$" + name, ""}}, 0, searchTextLimit}}
+}
+
+func searchDocumentResults(ctx context.Context, query string, terms *regexp.Regexp, limit int) ([]searchResult, error) {
+	var hits []struct {
+		models.Document `bson:",inline"`
+		TextScore       float64 `bson:"textScore"`
+	}
+	fields := bson.M{
+		"subject":   1,
+		"fileName":  1,
+		"createdAt": 1,
+		"content":   trimmedField("content"),
+		"text":      trimmedField("text"),
+	}
+	if err := textSearch(ctx, getDocumentCollection(), query, nil, fields, limit, &hits); err != nil {
+		return nil, err
+	}
+
+	results := make([]searchResult, 0, len(hits))
+	for _, h := range hits {
+		// Prefer the part of the file's text that matched
+		body := h.Content
+		if terms != nil && terms.MatchString(h.Text) && !terms.MatchString(body) {
+			body = h.Text
+		}
+		if body == "" {
+			body = h.FileName
+		}
+		results = append(results, searchResult{
+			Type:      "document",
+			ID:        h.ID,
+			Title:     h.Subject,
+			Snippet:   highlight(body, terms),
+			Score:     h.TextScore,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+func searchQuestionResults(ctx context.Context, query string, terms *regexp.Regexp, limit int) ([]searchResult, error) {
+	visible := bson.M{"moderationStatus": bson.M{"$exists": false}}
+
+	var questions []struct {
+		models.Qna `bson:",inline"`
+		TextScore  float64 `bson:"textScore"`
+	}
+	questionFields := bson.M{"question": 1, "createdAt": 1}
+	if err := textSearch(ctx, getQnaCollection(), query, visible, questionFields, limit, &questions); err != nil {
+		return nil, err
+	}
+
+	var answers []struct {
+		models.Answer `bson:",inline"`
+		TextScore     float64 `bson:"textScore"`
+	}
+	answerFields := bson.M{"questionId": 1, "createdAt": 1, "text": trimmedField("text")}
+	if err := textSearch(ctx, getAnswerCollection(), query, visible, answerFields, limit, &answers); err != nil {
+		return nil, err
+	}
+
+	results := make([]searchResult, 0, len(questions)+len(answers))
+	for _, q := range questions {
+		results = append(results, searchResult{
+			Type:      "question",
+			ID:        q.ID,
+			Title:     q.Question,
+			Snippet:   highlight(q.Question, terms),
+			Score:     q.TextScore,
+			CreatedAt: q.CreatedAt,
+		})
+	}
+
+	if len(answers) == 0 {
+		return results, nil
+	}
+
+	// Answers are titled with their question
+	var questionIDs []primitive.ObjectID
+	for _, a := range answers {
+		questionIDs = append(questionIDs, a.QuestionID)
+	}
+	cursor, err := getQnaCollection().Find(ctx,
+		bson.M{"_id": bson.M{"$in": questionIDs}, "moderationStatus": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"question": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var parents []models.Qna
+	if err := cursor.All(ctx, &parents); err != nil {
+		return nil, err
+	}
+	titles := map[primitive.ObjectID]string{}
+	for _, q := range parents {
+		titles[q.ID] = q.Question
+	}
+
+	for _, a := range answers {
+		title, ok := titles[a.QuestionID]
+		if !ok {
+			continue // question hidden or deleted
+		}
+		questionID := a.QuestionID
+		results = append(results, searchResult{
+			Type:       "answer",
+			ID:         a.ID,
+			QuestionID: &questionID,
+			Title:      title,
+			Snippet:    highlight(a.Text, terms),
+			Score:      a.TextScore,
+			CreatedAt:  a.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+func searchBlogResults(ctx context.Context, query string, terms *regexp.Regexp, limit int) ([]searchResult, error) {
+	var hits []struct {
+		models.Blog `bson:",inline"`
+		TextScore   float64 `bson:"textScore"`
+	}
+	visible := bson.M{"moderationStatus": bson.M{"$exists": false}}
+	fields := bson.M{"title": 1, "createdAt": 1, "content": trimmedField("content")}
+	if err := textSearch(ctx, GetBlogCollection(), query, visible, fields, limit, &hits); err != nil {
+		return nil, err
+	}
+
+	results := make([]searchResult, 0, len(hits))
+	for _, h := range hits {
+		results = append(results, searchResult{
+			Type:      "blog",
+			ID:        h.ID,
+			Title:     h.Title,
+			Snippet:   highlight(h.Content, terms),
+			Score:     h.TextScore,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+// Search looks for ?q= across documents, questions with their answers, and blogs,
+// best matches first. ?type=documents|questions|blogs restricts it to one kind.
+func Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	searchers := map[string]func(context.Context, string, *regexp.Regexp, int) ([]searchResult, error){
+		searchDocuments: searchDocumentResults,
+		searchQuestions: searchQuestionResults,
+		searchBlogs:     searchBlogResults,
+	}
+
+	types := []string{searchDocuments, searchQuestions, searchBlogs}
+	if t := c.Query("type"); t != "" {
+		if _, ok := searchers[t]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type, use documents, questions or blogs"})
+			return
+		}
+		types = []string{t}
+	}
+
+	page, limit := pageParams(c, 20)
+	if page > maxSearchPages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refine your search to see more results"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	// Each type returns enough hits to fill every page up to this one
+	terms := searchTerms(query)
+	results := []searchResult{}
+	for _, t := range types {
+		hits, err := searchers[t](ctx, query, terms, page*limit+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+			return
+		}
+		results = append(results, hits...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+
+	start := min((page-1)*limit, len(results))
+	end := min(start+limit, len(results))
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results[start:end],
+		"pagination": gin.H{
+			"page":    page,
+			"limit":   limit,
+			"hasMore": len(results) > end,
+		},
+	})
+}
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Uploads younger than this may still be waiting for their record to be saved
+	orphanGracePeriod = time.Hour
+
+	maxDeletionBackoff = 24 * time.Hour
+	sweepBatchSize     = 500
+)
+
+// Folders uploads are stored in (see utils.UploadFile and utils.UploadImage)
+var storageFolders = []string{"documents/", "blog_images/"}
+
+var (
+	storedFileCollection *mongo.Collection
+	storedFileOnce       sync.Once
+	storageSweeperOnce   sync.Once
+)
+
+// The stored_files collection queues files whose record was deleted, replaced
+// or never saved until they are removed from storage
+func getStoredFileCollection() *mongo.Collection {
+	storedFileOnce.Do(func() {
+		storedFileCollection = config.GetCollection("stored_files")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = storedFileCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "deleteAt", Value: 1}},
+		})
+
+		// Uploads used to be tracked here as well; the sweeper now lists storage itself
+		if _, err := storedFileCollection.DeleteMany(ctx, bson.M{"deleteAt": bson.M{"$exists": false}}); err != nil {
+			log.Println("⚠️ Failed to remove tracked uploads:", err)
+		}
+	})
+	return storedFileCollection
+}
+
+// releaseFile queues a file that is no longer referenced for deletion and
+// tries to delete it straight away. Failures stay queued for the sweeper.
+func releaseFile(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+
+	now := time.Now()
+	_, err := getStoredFileCollection().UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$set":         bson.M{"deleteAt": now},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Println("⚠️ Failed to queue file deletion:", key, err)
+	}
+
+	deleteStoredFile(ctx, key)
+}
+
+// releaseRecordFile releases the file of a deleted or replaced document or blog.
+// Keys derived from the URL of an older record may be shared, as old uploads
+// of the same file name went to one file, so those stay until unused.
+func releaseRecordFile(ctx context.Context, key, fileURL string) {
+	if key != "" {
+		releaseFile(ctx, key)
+		return
+	}
+
+	key = utils.StoredFileKey("", fileURL)
+	if key == "" {
+		return
+	}
+
+	inUse, err := fileInUse(ctx, key)
+	if err != nil {
+		// the sweeper deletes it once nothing references it
+		log.Println("⚠️ Failed to check whether file is still used:", key, err)
+		return
+	}
+	if !inUse {
+		releaseFile(ctx, key)
+	}
+}
+
+// deleteStoredFile removes the file from storage and, once that worked, from
+// the queue. A failure pushes the next attempt back exponentially.
+func deleteStoredFile(ctx context.Context, key string) {
+	storage := utils.GetStorage()
+	if storage == nil {
+		return
+	}
+
+	storedFileCollection := getStoredFileCollection()
+
+	err := storage.Delete(ctx, key)
+	if err == nil {
+		if _, err := storedFileCollection.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+			log.Println("⚠️ Failed to remove deleted file from queue:", key, err)
+		}
+		return
+	}
+
+	log.Println("⚠️ Failed to delete stored file, will retry:", key, err)
+
+	var file models.StoredFile
+	_ = storedFileCollection.FindOne(ctx, bson.M{"_id": key}).Decode(&file)
+
+	backoff := time.Minute << min(file.Attempts, 12)
+	if backoff > maxDeletionBackoff {
+		backoff = maxDeletionBackoff
+	}
+
+	_, updateErr := storedFileCollection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{
+		"$set": bson.M{"deleteAt": time.Now().Add(backoff), "lastError": err.Error()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	if updateErr != nil {
+		log.Println("⚠️ Failed to reschedule file deletion:", key, updateErr)
+	}
+}
+
+// StartStorageSweeper periodically retries queued file deletions and queues
+// stored files that no document or blog references
+func StartStorageSweeper(interval time.Duration) {
+	storageSweeperOnce.Do(func() {
+		go func() {
+			for {
+				sweepStorage()
+				time.Sleep(interval)
+			}
+		}()
+	})
+}
+
+func sweepStorage() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := queueOrphanedFiles(ctx); err != nil {
+		log.Println("⚠️ Failed to look for orphaned files:", err)
+	}
+	if err := retryFileDeletions(ctx); err != nil {
+		log.Println("⚠️ Failed to retry file deletions:", err)
+	}
+}
+
+// retryFileDeletions deletes every queued file that is due
+func retryFileDeletions(ctx context.Context) error {
+	opts := options.Find().SetSort(bson.D{{Key: "deleteAt", Value: 1}}).SetLimit(sweepBatchSize)
+	cursor, err := getStoredFileCollection().Find(ctx, bson.M{"deleteAt": bson.M{"$lte": time.Now()}}, opts)
+	if err != nil {
+		return err
+	}
+
+	var due []models.StoredFile
+	if err := cursor.All(ctx, &due); err != nil {
+		return err
+	}
+	for _, file := range due {
+		deleteStoredFile(ctx, file.Key)
+	}
+	return nil
+}
+
+// queueOrphanedFiles reconciles storage with the database, queueing files past
+// the grace period that no document or blog references
+func queueOrphanedFiles(ctx context.Context) error {
+	storage := utils.GetStorage()
+	if storage == nil {
+		return nil
+	}
+
+	// Load the references before listing, so a file saved meanwhile is young
+	referenced, err := referencedFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	var orphans []mongo.WriteModel
+	for _, folder := range storageFolders {
+		err := storage.List(ctx, folder, func(file utils.FileInfo) error {
+			if file.ModTime.Before(cutoff) && !referenced[fileID(file.Key)] {
+				now := time.Now()
+				orphans = append(orphans, mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"_id": file.Key}).
+					SetUpdate(bson.M{"$setOnInsert": bson.M{"createdAt": now, "deleteAt": now}}).
+					SetUpsert(true))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	// Files already queued keep their retry schedule
+	result, err := getStoredFileCollection().BulkWrite(ctx, orphans, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return err
+	}
+	if result.UpsertedCount > 0 {
+		log.Printf("🧹 Queued %d orphaned files for deletion", result.UpsertedCount)
+	}
+	return nil
+}
+
+// fileSource is a collection whose records use stored files
+type fileSource struct {
+	collection *mongo.Collection
+	keyField   string
+	urlField   string
+}
+
+func fileSources() []fileSource {
+	return []fileSource{
+		{getDocumentCollection(), "storageKey", "fileUrl"},
+		{GetBlogCollection(), "imageKey", "imageUrl"},
+	}
+}
+
+// referencedFiles returns the fileID of every file a document or blog uses,
+// including those saved before keys were stored
+func referencedFiles(ctx context.Context) (map[string]bool, error) {
+	referenced := map[string]bool{}
+
+	for _, source := range fileSources() {
+		err := eachFileKey(ctx, source, bson.M{}, func(key string) bool {
+			referenced[fileID(key)] = true
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return referenced, nil
+}
+
+// fileInUse reports whether any document or blog uses the file, matching
+// records saved before keys were stored through their URLs
+func fileInUse(ctx context.Context, key string) (bool, error) {
+	id := fileID(key)
+
+	for _, source := range fileSources() {
+		count, err := source.collection.CountDocuments(ctx, bson.M{source.keyField: key})
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+
+		inUse := false
+		filter := bson.M{source.keyField: bson.M{"$exists": false}, source.urlField: bson.M{"$nin": bson.A{nil, ""}}}
+		err = eachFileKey(ctx, source, filter, func(key string) bool {
+			inUse = fileID(key) == id
+			return !inUse
+		})
+		if err != nil || inUse {
+			return inUse, err
+		}
+	}
+	return false, nil
+}
+
+// eachFileKey calls fn with the key of the file each matching record uses,
+// until fn returns false
+func eachFileKey(ctx context.Context, source fileSource, filter bson.M, fn func(key string) bool) error {
+	opts := options.Find().SetProjection(bson.M{source.keyField: 1, source.urlField: 1})
+	cursor, err := source.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		key, _ := cursor.Current.Lookup(source.keyField).StringValueOK()
+		url, _ := cursor.Current.Lookup(source.urlField).StringValueOK()
+		if key := utils.StoredFileKey(key, url); key != "" && !fn(key) {
+			return nil
+		}
+	}
+	return cursor.Err()
+}
+
+// fileID is a key without its extension, which Cloudinary does not keep, so
+// keys from Storage.List can be matched with the keys records were saved with
+func fileID(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key))
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	previewLength     = 1000
+	maxExtractionFile = 20 << 20
+	extractionQueue   = 100
+)
+
+// extractionJob asks a worker to extract a document's text. Data is the uploaded
+// file when it is still in memory; otherwise the worker downloads it.
+type extractionJob struct {
+	DocumentID primitive.ObjectID
+	Data       []byte
+}
+
+var (
+	extractionJobs chan extractionJob
+	extractionOnce sync.Once
+	downloadClient = &http.Client{Timeout: time.Minute}
+)
+
+// StartTextExtraction starts the background workers that extract text from
+// uploaded documents, and queues documents left pending by a previous run
+func StartTextExtraction(workers int) {
+	extractionOnce.Do(func() {
+		extractionJobs = make(chan extractionJob, extractionQueue)
+		for i := 0; i < workers; i++ {
+			go extractionWorker()
+		}
+
+		go requeuePendingExtractions()
+	})
+}
+
+// enqueueExtraction hands a document to the workers. When the queue is full the
+// document stays pending and is picked up on the next start.
+func enqueueExtraction(job extractionJob) {
+	if extractionJobs == nil {
+		return
+	}
+
+	select {
+	case extractionJobs <- job:
+	default:
+		log.Println("⚠️ Text extraction queue is full, leaving document pending:", job.DocumentID.Hex())
+	}
+}
+
+func requeuePendingExtractions() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(extractionQueue)
+	cursor, err := getDocumentCollection().Find(ctx, bson.M{"extraction": models.ExtractionPending}, opts)
+	if err != nil {
+		log.Println("⚠️ Failed to load pending text extractions:", err)
+		return
+	}
+
+	var pending []models.Document
+	if err := cursor.All(ctx, &pending); err != nil {
+		log.Println("⚠️ Failed to load pending text extractions:", err)
+		return
+	}
+	for _, doc := range pending {
+		extractionJobs <- extractionJob{DocumentID: doc.ID}
+	}
+}
+
+func extractionWorker() {
+	for job := range extractionJobs {
+		if err := extractDocumentText(job); err != nil {
+			log.Printf("⚠️ Text extraction failed for document %s: %v", job.DocumentID.Hex(), err)
+		}
+	}
+}
+
+// extractDocumentText extracts the document's text and stores it with a preview
+func extractDocumentText(job extractionJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	documentCollection := getDocumentCollection()
+
+	var doc models.Document
+	if err := documentCollection.FindOne(ctx, bson.M{"_id": job.DocumentID}).Decode(&doc); err != nil {
+		return err // deleted meanwhile
+	}
+
+	data := job.Data
+	if data == nil {
+		var err error
+		if data, err = readDocumentFile(ctx, doc); err != nil {
+			return err
+		}
+	}
+
+	fields := bson.M{"extraction": models.ExtractionDone}
+	pages, err := utils.ExtractText(data, doc.FileName)
+	switch {
+	case errors.Is(err, utils.ErrUnsupportedFormat):
+		fields["extraction"] = models.ExtractionUnsupported
+	case err != nil:
+		fields["extraction"] = models.ExtractionFailed
+	default:
+		fields["text"] = truncateRunes(strings.Join(pages, "\n\n"), utils.MaxExtractedText)
+		for _, page := range pages {
+			if page != "" {
+				fields["preview"] = truncateRunes(page, previewLength)
+				break
+			}
+		}
+	}
+
+	if _, updateErr := documentCollection.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": fields}); updateErr != nil {
+		return updateErr
+	}
+	if errors.Is(err, utils.ErrUnsupportedFormat) {
+		return nil
+	}
+	return err
+}
+
+// readDocumentFile reads the file from storage, or from its URL for documents
+// uploaded before storage keys were recorded
+func readDocumentFile(ctx context.Context, doc models.Document) ([]byte, error) {
+	storage := utils.GetStorage()
+	if doc.StorageKey == "" || storage == nil {
+		return downloadFile(ctx, doc.FileUrl)
+	}
+
+	r, err := storage.Get(ctx, doc.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(io.LimitReader(r, maxExtractionFile))
+}
+
+func downloadFile(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxExtractionFile))
+}
+
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer        = "PrepPortal"
+	recoveryCodeCount = 10
+)
+
+// useTOTPCode checks a code and records its time step so it cannot be used twice
+func useTOTPCode(ctx context.Context, user models.User, code string) bool {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep, time.Now())
+	if !ok {
+		return false
+	}
+
+	// Guard against two requests racing with the same code
+	result, err := getUserCollection().UpdateOne(ctx,
+		bson.M{"_id": user.ID, "$or": []bson.M{
+			{"totpLastStep": bson.M{"$exists": false}},
+			{"totpLastStep": bson.M{"$lt": step}},
+		}},
+		bson.M{"$set": bson.M{"totpLastStep": step}},
+	)
+	return err == nil && result.ModifiedCount == 1
+}
+
+// useRecoveryCode consumes one of the user's recovery codes
+func useRecoveryCode(ctx context.Context, user models.User, code string) bool {
+	hash := utils.HashRecoveryCode(code)
+
+	result, err := getUserCollection().UpdateOne(ctx,
+		bson.M{"_id": user.ID, "recoveryCodes": hash},
+		bson.M{"$pull": bson.M{"recoveryCodes": hash}},
+	)
+	return err == nil && result.ModifiedCount == 1
+}
+
+// EnrollTwoFactor creates a pending TOTP secret for the authenticated user
+func EnrollTwoFactor(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"totpPending": secret}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":     secret,
+		"otpauthUrl": utils.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmTwoFactor enables TOTP once the user proves their app produces valid codes.
+// It returns the recovery codes (shown only once) and a two-factor session.
+func ConfirmTwoFactor(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := c.BindJSON(&request); err != nil || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.TOTPPending == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(user.TOTPPending, request.Code, 0, time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"totpEnabled":   true,
+			"totpSecret":    user.TOTPPending,
+			"totpLastStep":  step,
+			"recoveryCodes": hashes,
+			"updatedAt":     time.Now(),
+		},
+		"$unset": bson.M{"totpPending": ""},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	// The user just proved both factors, so upgrade them to a two-factor session
+	if sessionID, err := primitive.ObjectIDFromHex(c.GetString("sessionID")); err == nil {
+		_ = utils.RevokeSession(ctx, sessionID)
+	}
+	token, refreshToken, err := issueTokens(c, ctx, user, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Two-factor authentication enabled",
+		"recoveryCodes": codes,
+		"token":         token,
+		"refreshToken":  refreshToken,
+	})
+}
+
+// VerifyTwoFactor exchanges a login challenge token and a TOTP or recovery code for real tokens
+func VerifyTwoFactor(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	var request struct {
+		ChallengeToken string `json:"challengeToken"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recoveryCode"`
+	}
+	if err := c.BindJSON(&request); err != nil || (request.Code == "" && request.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	subject, err := utils.ValidateChallengeToken(request.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please log in again"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(subject)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please log in again"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge, please log in again"})
+		return
+	}
+
+	// Codes are only six digits, so guesses count against the login throttle too
+	throttle := utils.GetLoginThrottle()
+	wait, err := throttle.Attempt(ctx, user.Email, c.ClientIP())
+	if err != nil {
+		log.Println("⚠️ Login throttle check failed:", err)
+	}
+	if wait > 0 {
+		tooManyAttempts(c, wait)
+		return
+	}
+
+	var valid bool
+	if request.Code != "" {
+		valid = useTOTPCode(ctx, user, request.Code)
+	} else {
+		valid = useRecoveryCode(ctx, user, request.RecoveryCode)
+	}
+	if !valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	if err := throttle.Success(ctx, user.Email, c.ClientIP()); err != nil {
+		log.Println("⚠️ Failed to reset login attempts:", err)
+	}
+
+	token, refreshToken, err := issueTokens(c, ctx, user, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "token": token, "refreshToken": refreshToken})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current TOTP code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := c.BindJSON(&request); err != nil || request.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.TOTPEnabled || !useTOTPCode(ctx, user, request.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	_, err = userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"recoveryCodes": hashes}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// DisableTwoFactor turns TOTP off after checking the password and a current code
+func DisableTwoFactor(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if !user.TOTPEnabled || !useTOTPCode(ctx, user, request.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	_, err := userCollection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set":   bson.M{"totpEnabled": false, "updatedAt": time.Now()},
+		"$unset": bson.M{"totpSecret": "", "totpLastStep": "", "recoveryCodes": ""},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	// Existing sessions were established with a second factor that no longer exists
+	if err := utils.RevokeAllSessions(ctx, user.ID); err != nil {
+		log.Println("⚠️ Failed to revoke sessions after disabling 2FA:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled, please log in again"})
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	emailVerificationTTL = 48 * time.Hour
+	emailChangeTTL       = 24 * time.Hour
+)
+
+// apiBaseURL is the public URL of this API, used in emailed links
+func apiBaseURL() string {
+	if base := os.Getenv("API_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// sendVerificationEmail emails a fresh verification link to the user's address
+func sendVerificationEmail(ctx context.Context, user models.User) error {
+	token, err := utils.CreateUserToken(ctx, user.ID, models.TokenPurposeEmailVerification, user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify?token=%s", apiBaseURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your PrepPortal email address by opening the link below:\n\n%s\n\nThe link expires in 48 hours.", user.FullName, link)
+
+	return utils.SendMail(user.Email, "Verify your PrepPortal email", body)
+}
+
+// VerifyEmail marks the user's email as verified using the emailed token
+func VerifyEmail(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	rawToken := c.Query("token")
+	if rawToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification token is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := utils.ConsumeUserToken(ctx, rawToken, models.TokenPurposeEmailVerification)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	// The address must still be the one the link was sent to
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": token.UserID, "email": token.Email},
+		bson.M{"$set": bson.M{"emailVerified": true, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully, please refresh your session"})
+}
+
+// ResendVerification emails a new verification link to the authenticated user
+func ResendVerification(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.EmailVerified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := sendVerificationEmail(ctx, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// sendEmailChangeConfirmation emails a link confirming the change to newEmail
+func sendEmailChangeConfirmation(ctx context.Context, user models.User, newEmail string) error {
+	token, err := utils.CreateUserToken(ctx, user.ID, models.TokenPurposeEmailChange, newEmail, emailChangeTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/email/confirm?token=%s", apiBaseURL(), url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm that your PrepPortal account should use this email address by opening the link below:\n\n%s\n\nThe link expires in 24 hours. If you did not ask for this, ignore this email.", user.FullName, link)
+
+	return utils.SendMail(newEmail, "Confirm your new PrepPortal email", body)
+}
+
+// ConfirmEmailChange switches the account to the new address using the emailed token.
+// Opening the link proves the address, so it counts as verified.
+func ConfirmEmailChange(c *gin.Context) {
+	userCollection := getUserCollection()
+
+	rawToken := c.Query("token")
+	if rawToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Confirmation token is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := utils.ConsumeUserToken(ctx, rawToken, models.TokenPurposeEmailChange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+
+	var user models.User
+	err = userCollection.FindOne(ctx, bson.M{"_id": token.UserID, "pendingEmail": token.Email}).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+
+	// The unique index settles concurrent claims on the same address
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"_id": user.ID, "pendingEmail": token.Email},
+		bson.M{
+			"$set":   bson.M{"email": token.Email, "emailVerified": true, "updatedAt": time.Now()},
+			"$unset": bson.M{"pendingEmail": ""},
+		},
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+
+	// Blogs record their author by email
+	if _, err := GetBlogCollection().UpdateMany(ctx, bson.M{"author": user.Email}, bson.M{"$set": bson.M{"author": token.Email}}); err != nil {
+		log.Println("⚠️ Failed to move blogs to new email:", err)
+	}
+
+	// Tokens carrying the old email must stop working
+	if err := utils.RevokeAllSessions(ctx, user.ID); err != nil {
+		log.Println("⚠️ Failed to revoke sessions after email change:", err)
+	}
+
+	notice := fmt.Sprintf("Hi %s,\n\nThe email address of your PrepPortal account was changed to %s.\n\nIf this wasn't you, contact us right away.", user.FullName, token.Email)
+	if err := utils.SendMail(user.Email, "Your PrepPortal email was changed", notice); err != nil {
+		log.Println("⚠️ Failed to notify old email address:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed, please log in again"})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// connectTestDB connects to the throwaway database in TEST_MONGO_URI, skipping
+// the test without one. Never point it at a database holding real data.
+func connectTestDB(t *testing.T) {
+	t.Helper()
+
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	t.Setenv("MONGO_URI", uri)
+	config.ConnectDB()
+}
+
+var verificationLink = regexp.MustCompile(`/auth/verify\?token=(\S+)`)
+
+func TestSignupThenVerifyEmail(t *testing.T) {
+	connectTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	ring, err := utils.LoadKeyRing("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utils.SetKeyRing(ring)
+
+	mailer := &utils.MemoryMailer{Quiet: true}
+	utils.SetMailer(mailer)
+	t.Cleanup(func() { utils.SetMailer(&utils.MemoryMailer{}) })
+
+	router := gin.New()
+	router.POST("/auth/register", Signup)
+	router.GET("/auth/verify", VerifyEmail)
+
+	email := "signup-" + primitive.NewObjectID().Hex() + "@example.com"
+	t.Cleanup(func() {
+		getUserCollection().DeleteOne(context.Background(), bson.M{"email": email})
+	})
+
+	body := `{"fullName":"Ada Lovelace","email":"` + email + `","password":"correct horse battery"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("signup status = %d: %s", w.Code, w.Body)
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 1 || messages[0].To != email {
+		t.Fatalf("sent emails = %+v, want one verification email to %s", messages, email)
+	}
+	match := verificationLink.FindStringSubmatch(messages[0].Body)
+	if match == nil {
+		t.Fatalf("no verification link in %q", messages[0].Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var user models.User
+	if err := getUserCollection().FindOne(context.Background(), bson.M{"email": email}).Decode(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.EmailVerified {
+		t.Fatal("email verified before the link was opened")
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/verify?token="+url.QueryEscape(token), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("verify status = %d: %s", w.Code, w.Body)
+	}
+
+	if err := getUserCollection().FindOne(context.Background(), bson.M{"email": email}).Decode(&user); err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerified {
+		t.Fatal("email not verified after opening the link")
+	}
+
+	// each link works once
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/verify?token="+url.QueryEscape(token), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("second verify status = %d, want 400", w.Code)
+	}
+}
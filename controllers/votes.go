@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	voteCollection *mongo.Collection
+	voteOnce       sync.Once
+)
+
+func getVoteCollection() *mongo.Collection {
+	voteOnce.Do(func() {
+		voteCollection = config.GetCollection("votes")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// One vote per user per target
+		_, _ = voteCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "targetId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+	})
+	return voteCollection
+}
+
+// parseVoteType maps the request's voteType to a direction. Sending the same
+// direction as the existing vote toggles it off; "none" retracts it.
+func parseVoteType(voteType string) (int, bool) {
+	switch voteType {
+	case "upvote":
+		return 1, true
+	case "downvote":
+		return -1, true
+	case "none", "retract":
+		return 0, true
+	}
+	return 0, false
+}
+
+// castVote records the user's vote on a target and returns the previous and new direction
+func castVote(ctx context.Context, userID, targetID primitive.ObjectID, targetType string, direction int) (int, int, error) {
+	votes := getVoteCollection()
+	key := bson.M{"userId": userID, "targetId": targetID}
+
+	var existing models.Vote
+	err := votes.FindOne(ctx, key).Decode(&existing)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, 0, err
+	}
+
+	// Retracting, or voting the same way twice, removes the vote
+	if direction == 0 || (err == nil && existing.Direction == direction) {
+		var removed models.Vote
+		err := votes.FindOneAndDelete(ctx, key).Decode(&removed)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, 0, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		return removed.Direction, 0, nil
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set":         bson.M{"direction": direction, "updatedAt": now},
+		"$setOnInsert": bson.M{"targetType": targetType, "createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var before models.Vote
+	err = votes.FindOneAndUpdate(ctx, key, update, opts).Decode(&before)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent first vote won the upsert, apply ours on top of it
+		err = votes.FindOneAndUpdate(ctx, key, update, opts).Decode(&before)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, direction, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return before.Direction, direction, nil
+}
+
+// voteCounterDelta turns a vote change into increments for the upvotes/downvotes counters
+func voteCounterDelta(previous, current int) (int, int) {
+	up, down := 0, 0
+	if previous == 1 {
+		up--
+	} else if previous == -1 {
+		down--
+	}
+	if current == 1 {
+		up++
+	} else if current == -1 {
+		down++
+	}
+	return up, down
+}
+
+// votePoints is the reputation a post's author holds from one vote in the given direction
+func votePoints(targetType string, direction int) int {
+	switch {
+	case targetType == models.VoteTargetQuestion && direction == 1:
+		return pointsQuestionUpvote
+	case targetType == models.VoteTargetQuestion && direction == -1:
+		return pointsQuestionDownvote
+	case targetType == models.VoteTargetAnswer && direction == 1:
+		return pointsAnswerUpvote
+	case targetType == models.VoteTargetAnswer && direction == -1:
+		return pointsAnswerDownvote
+	}
+	return 0
+}
+
+// userVotes returns the user's vote direction for each of the given targets
+func userVotes(ctx context.Context, userID primitive.ObjectID, targetIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	result := map[primitive.ObjectID]int{}
+	if len(targetIDs) == 0 {
+		return result, nil
+	}
+
+	cursor, err := getVoteCollection().Find(ctx, bson.M{"userId": userID, "targetId": bson.M{"$in": targetIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	var votes []models.Vote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, err
+	}
+	for _, vote := range votes {
+		result[vote.TargetID] = vote.Direction
+	}
+
+	return result, nil
+}
+
+// fillMyVotes sets MyVote on the questions and their answers for the given user
+func fillMyVotes(ctx context.Context, userID primitive.ObjectID, questions []models.Qna) error {
+	var ids []primitive.ObjectID
+	for _, q := range questions {
+		ids = append(ids, q.ID)
+		for _, a := range q.Answers {
+			ids = append(ids, a.ID)
+		}
+	}
+
+	votes, err := userVotes(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range questions {
+		questions[i].MyVote = votes[questions[i].ID]
+		for j := range questions[i].Answers {
+			questions[i].Answers[j].MyVote = votes[questions[i].Answers[j].ID]
+		}
+	}
+	return nil
+}
@@ -1,23 +1,31 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/controllers"
 	"github.com/tr-choudhury21/prepportal_backend/routes"
+	"github.com/tr-choudhury21/prepportal_backend/utils"
 )
 
 func main() {
+	migrateAnswers := flag.Bool("migrate-answers", false, "move answers embedded in questions into the answers collection, then exit")
+	promoteAdmin := flag.String("promote-admin", "", "give the user with this email the admin role, then exit")
+	flag.Parse()
 
 	err := godotenv.Load()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Println("⚠️ No .env file, using the environment as is")
 	}
 
 	//connect db
@@ -29,11 +37,62 @@ func main() {
 		fmt.Println("✅ MongoDB connection is ready.")
 	}
 
+	if *migrateAnswers {
+		if _, err := controllers.MigrateEmbeddedAnswers(context.Background()); err != nil {
+			log.Fatal("❌ Answer migration failed: ", err)
+		}
+		return
+	}
+
+	if *promoteAdmin != "" {
+		if err := controllers.PromoteToAdmin(context.Background(), *promoteAdmin); err != nil {
+			log.Fatal("❌ Failed to promote admin: ", err)
+		}
+		fmt.Println("✅ Promoted", *promoteAdmin, "to admin")
+		return
+	}
+
 	//connect cloudinary
 	config.InitCloudinary()
 
+	//file storage (Cloudinary, S3 or local disk)
+	utils.InitStorage()
+
+	//email delivery
+	utils.InitMailer()
+
+	//jwt signing keys
+	utils.InitKeyRing()
+
+	//login brute-force protection
+	utils.InitLoginThrottle()
+
+	//"Sign in with ..." providers
+	utils.InitOIDCProviders()
+
+	//text indexes used by /search
+	controllers.EnsureSearchIndexes()
+
+	//text extraction for document search and previews
+	controllers.StartTextExtraction(2)
+
+	//retry failed file deletions and remove orphaned uploads
+	controllers.StartStorageSweeper(time.Hour)
+
 	router := gin.Default()
 
+	// Client IPs (login throttling, view counts) only come from X-Forwarded-For
+	// when the request passed through one of TRUSTED_PROXIES, comma separated
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("❌ Invalid TRUSTED_PROXIES: ", err)
+	}
+
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "https://yourfrontend.com"}, // Change accordingly
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -48,6 +107,10 @@ func main() {
 	routes.DocumentRoutes(router)
 	routes.QnaRoutes(router)
 	routes.BlogRoutes(router)
+	routes.ModerationRoutes(router)
+	routes.SearchRoutes(router)
+	routes.FileRoutes(router)
+	routes.WellKnownRoutes(router)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
 	"github.com/tr-choudhury21/prepportal_backend/utils"
 )
 
@@ -43,13 +46,57 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Store user email in the context
-		c.Set("userEmail", claims.Email)
-		c.Set("fullName", claims.FullName) // Ensure this is set correctly
-		c.Set("userID", claims.UserID)     // Store user ID if needed
+		// Reject tokens whose session was logged out or revoked
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if !utils.IsSessionActive(ctx, claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has been revoked!",
+			})
+			c.Abort()
+			return
+		}
+
+		setClaims(c, claims)
 
 		// Continue to the next middleware/handler
 		c.Next()
 
 	}
 }
+
+// setClaims stores the authenticated user's details in the request context
+func setClaims(c *gin.Context, claims *utils.Claims) {
+	// Store user email in the context
+	c.Set("userEmail", claims.Email)
+	c.Set("fullName", claims.FullName) // Ensure this is set correctly
+	c.Set("userID", claims.UserID)     // Store user ID if needed
+	c.Set("sessionID", claims.SessionID)
+	c.Set("userRole", models.NormalizeRole(claims.Role))
+	c.Set("emailVerified", claims.EmailVerified)
+	c.Set("mfa", claims.MFA)
+}
+
+// OptionalAuth identifies the user when a valid token is sent, but lets anonymous
+// requests through, for public endpoints that personalise their response
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tokenString, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found {
+			c.Next()
+			return
+		}
+
+		claims, err := utils.ValidateToken(tokenString)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if utils.IsSessionActive(ctx, claims.SessionID) {
+				setClaims(c, claims)
+			}
+		}
+
+		c.Next()
+	}
+}
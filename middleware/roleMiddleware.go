@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+)
+
+// RequireRole only lets through users holding one of the given roles.
+// Moderators and admins must also have logged in with two-factor authentication.
+// It must run after AuthMiddleware, which puts the role in the context.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		role := c.GetString("userRole")
+		for _, allowed := range roles {
+			if role != allowed {
+				continue
+			}
+
+			if models.IsModeratorRole(role) && !c.GetBool("mfa") {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "Two-factor authentication is required for this action!",
+				})
+				c.Abort()
+				return
+			}
+
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You do not have permission to perform this action!",
+		})
+		c.Abort()
+	}
+}
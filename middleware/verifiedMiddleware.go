@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail blocks users who have not verified their email address yet.
+// It must run after AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		if !c.GetBool("emailVerified") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Please verify your email address first!",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
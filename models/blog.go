@@ -7,12 +7,14 @@ import (
 )
 
 type Blog struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	Title     string             `bson:"title" json:"title"`
-	Content   string             `bson:"content" json:"content"`
-	ImageURL  string             `bson:"imageUrl,omitempty" json:"imageUrl"`
-	Author    string             `bson:"author" json:"author"`
-	AuthorID  primitive.ObjectID `bson:"author_id" json:"author_id"`
-	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID               primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Title            string             `bson:"title" json:"title"`
+	Content          string             `bson:"content" json:"content"`
+	ImageURL         string             `bson:"imageUrl,omitempty" json:"imageUrl"`
+	ImageKey         string             `bson:"imageKey,omitempty" json:"-"` // image's key in utils.Storage
+	Author           string             `bson:"author" json:"author"`
+	AuthorID         primitive.ObjectID `bson:"author_id" json:"author_id"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt        time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ModerationStatus string             `bson:"moderationStatus,omitempty" json:"moderationStatus,omitempty"`
 }
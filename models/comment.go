@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comment is a short remark on an answer, kept in its own collection so busy
+// questions don't outgrow their document. Replies set ParentID to a top-level
+// comment; replies to replies are attached to the same parent.
+type Comment struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	AnswerID   primitive.ObjectID  `bson:"answerId" json:"answerId"`
+	QuestionID primitive.ObjectID  `bson:"questionId" json:"questionId"`
+	ParentID   *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	Text       string              `bson:"text" json:"text"`
+	PostedBy   string              `bson:"postedBy" json:"postedBy"`
+	PostedByID primitive.ObjectID  `bson:"postedById" json:"postedById"`
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+	EditedAt   *time.Time          `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+	Upvotes    int                 `bson:"upvotes" json:"upvotes"`
+	Downvotes  int                 `bson:"downvotes" json:"downvotes"`
+	ReplyCount int                 `bson:"replyCount" json:"replyCount"`
+	MyVote     int                 `bson:"-" json:"myVote"` // caller's vote, filled in when listing
+	Replies    []Comment           `bson:"-" json:"replies,omitempty"`
+}
@@ -6,6 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Progress of extracting a document's text for search and previews
+const (
+	ExtractionPending     = "pending"
+	ExtractionDone        = "done"
+	ExtractionFailed      = "failed"
+	ExtractionUnsupported = "unsupported"
+)
+
 type Document struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Subject    string             `bson:"subject" json:"subject"`
@@ -15,7 +23,13 @@ type Document struct {
 	Content    string             `bson:"content" json:"content"`
 	FileUrl    string             `bson:"fileUrl" json:"fileUrl"`
 	FileName   string             `bson:"fileName" json:"fileName"`
+	StorageKey string             `bson:"storageKey,omitempty" json:"-"` // file's key in utils.Storage
 	UploadedBy string             `bson:"uploadedBy" json:"uploadedBy"`
+	UploaderID primitive.ObjectID `bson:"uploaderId,omitempty" json:"uploaderId"`
+	Downloads  int                `bson:"downloads" json:"downloads"`
+	Text       string             `bson:"text,omitempty" json:"-"`                // extracted text, for search
+	Preview    string             `bson:"preview,omitempty" json:"preview"`       // start of the first page
+	Extraction string             `bson:"extraction,omitempty" json:"extraction"` // Extraction* status
 	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
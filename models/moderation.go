@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of content that can be reported
+const (
+	ReportTargetQuestion = "question"
+	ReportTargetAnswer   = "answer"
+	ReportTargetBlog     = "blog"
+)
+
+// Moderation status of a question, answer or blog. Visible content has none.
+const (
+	ModerationHidden  = "hidden"  // hidden automatically after enough reports
+	ModerationRemoved = "removed" // taken down by a moderator
+)
+
+// Report states
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportActioned  = "actioned"
+)
+
+// ContentReport is one user's report about a question, answer or blog.
+// Each user can report a given item once.
+type ContentReport struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	TargetID   primitive.ObjectID  `bson:"targetId" json:"targetId"`
+	TargetType string              `bson:"targetType" json:"targetType"`
+	QuestionID primitive.ObjectID  `bson:"questionId,omitempty" json:"questionId,omitempty"` // for answers
+	ReporterID primitive.ObjectID  `bson:"reporterId" json:"reporterId"`
+	Reason     string              `bson:"reason" json:"reason"`
+	Trusted    bool                `bson:"trusted,omitempty" json:"trusted"` // reporter had the reputation to count toward auto-hiding
+	Status     string              `bson:"status" json:"status"`
+	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
+	ResolvedAt *time.Time          `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	ResolvedBy *primitive.ObjectID `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+}
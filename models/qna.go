@@ -8,29 +8,45 @@ import (
 
 // Qna Model
 type Qna struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Question  string             `bson:"question" json:"question"`
-	Answers   []Answer           `bson:"answers" json:"answers"`
-	PostedBy  string             `bson:"postedBy" json:"postedBy"`
-	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
-	Reports   []Report           `bson:"reports" json:"reports"`
-	Upvotes   int                `bson:"upvotes" json:"upvotes"`
-	Downvotes int                `bson:"downvotes" json:"downvotes"`
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Question         string              `bson:"question" json:"question"`
+	Tags             []string            `bson:"tags" json:"tags"`
+	Branch           string              `bson:"branch,omitempty" json:"branch"`
+	Semester         string              `bson:"semester,omitempty" json:"semester"`
+	Subject          string              `bson:"subject,omitempty" json:"subject"`
+	Answers          []Answer            `bson:"-" json:"answers"` // top answers preview, stored in the answers collection
+	AnswerCount      int                 `bson:"answerCount" json:"answerCount"`
+	PostedBy         string              `bson:"postedBy" json:"postedBy"`
+	PostedByID       primitive.ObjectID  `bson:"postedById,omitempty" json:"postedById"`
+	CreatedAt        time.Time           `bson:"createdAt" json:"createdAt"`
+	Upvotes          int                 `bson:"upvotes" json:"upvotes"`
+	Downvotes        int                 `bson:"downvotes" json:"downvotes"`
+	Score            int                 `bson:"score" json:"score"`       // upvotes - downvotes
+	HotScore         float64             `bson:"hotScore" json:"hotScore"` // score decayed by age, for sort=hot
+	LastActivityAt   time.Time           `bson:"lastActivityAt" json:"lastActivityAt"`
+	ViewCount        int                 `bson:"viewCount" json:"viewCount"`
+	MyVote           int                 `bson:"-" json:"myVote"` // caller's vote, filled in when listing
+	AcceptedAnswerID *primitive.ObjectID `bson:"acceptedAnswerId,omitempty" json:"acceptedAnswerId"`
+	Resolved         bool                `bson:"resolved" json:"resolved"`
+	EditedAt         *time.Time          `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+	ModerationStatus string              `bson:"moderationStatus,omitempty" json:"moderationStatus,omitempty"`
+	DuplicateOfID    *primitive.ObjectID `bson:"duplicateOf,omitempty" json:"duplicateOf,omitempty"` // set when closed as a duplicate
+	ClosedBy         *primitive.ObjectID `bson:"closedBy,omitempty" json:"closedBy,omitempty"`
+	ClosedAt         *time.Time          `bson:"closedAt,omitempty" json:"closedAt,omitempty"`
 }
 
 // Answer model
 type Answer struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Text      string             `bson:"text" json:"text"`
-	PostedBy  string             `bson:"postedBy" json:"postedBy"`
-	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
-	Upvotes   int                `bson:"upvotes" json:"upvotes"`
-	Downvotes int                `bson:"downvotes" json:"downvotes"`
-}
-
-// Report Model
-type Report struct {
-	ReportedBy primitive.ObjectID `bson:"reportedBy" json:"reportedBy"`
-	Reason     string             `bson:"reason" json:"reason"`
-	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	QuestionID       primitive.ObjectID `bson:"questionId" json:"questionId"`
+	Text             string             `bson:"text" json:"text"`
+	PostedBy         string             `bson:"postedBy" json:"postedBy"`
+	PostedByID       primitive.ObjectID `bson:"postedById,omitempty" json:"postedById"`
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	Upvotes          int                `bson:"upvotes" json:"upvotes"`
+	Downvotes        int                `bson:"downvotes" json:"downvotes"`
+	CommentCount     int                `bson:"commentCount" json:"commentCount"`
+	MyVote           int                `bson:"-" json:"myVote"` // caller's vote, filled in when listing
+	EditedAt         *time.Time         `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+	ModerationStatus string             `bson:"moderationStatus,omitempty" json:"moderationStatus,omitempty"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons for reputation changes
+const (
+	ReputationDocumentUploaded = "document_uploaded"
+	ReputationBlogPublished    = "blog_published"
+	ReputationQuestionVoted    = "question_voted"
+	ReputationAnswerVoted      = "answer_voted"
+	ReputationAnswerAccepted   = "answer_accepted"
+)
+
+// ReputationEvent is one entry of the reputation ledger. A user's Reputation is
+// the sum of the points of their events.
+type ReputationEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"userId" json:"userId"`
+	Points    int                `bson:"points" json:"points"`
+	Reason    string             `bson:"reason" json:"reason"`
+	SourceID  primitive.ObjectID `bson:"sourceId" json:"sourceId"` // document, blog, question or answer
+	Key       string             `bson:"key,omitempty" json:"-"`   // set for awards that may only happen once
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// What happened to the content a revision preserves
+const (
+	RevisionEdited  = "edited"
+	RevisionDeleted = "deleted"
+)
+
+// Revision keeps a previous version of a question or answer, saved whenever
+// it is edited or deleted. EditedBy is whoever made the change.
+type Revision struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TargetID   primitive.ObjectID `bson:"targetId" json:"targetId"`
+	TargetType string             `bson:"targetType" json:"targetType"` // VoteTargetQuestion or VoteTargetAnswer
+	QuestionID primitive.ObjectID `bson:"questionId" json:"questionId"`
+	Text       string             `bson:"text" json:"text"`
+	Action     string             `bson:"action" json:"action"`
+	EditedBy   string             `bson:"editedBy" json:"editedBy"`
+	EditedByID primitive.ObjectID `bson:"editedById" json:"editedById"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
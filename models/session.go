@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session represents a refresh-token backed login on one device
+type Session struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID `bson:"userId" json:"userId"`
+	RefreshTokenHash string             `bson:"refreshTokenHash" json:"-"`
+	PreviousHashes   []string           `bson:"previousHashes" json:"-"`
+	UserAgent        string             `bson:"userAgent" json:"userAgent"`
+	IP               string             `bson:"ip" json:"ip"`
+	MFA              bool               `bson:"mfa" json:"mfa"` // logged in with a second factor
+	CreatedAt        time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt       time.Time          `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt        time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt        *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
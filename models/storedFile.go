@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// StoredFile is a file queued for deletion from storage, keyed by its storage
+// key. Until the deletion succeeds it is retried at DeleteAt, with LastError
+// recording the latest failure.
+type StoredFile struct {
+	Key       string     `bson:"_id" json:"key"`
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+	DeleteAt  *time.Time `bson:"deleteAt,omitempty" json:"deleteAt,omitempty"`
+	Attempts  int        `bson:"attempts,omitempty" json:"attempts,omitempty"`
+	LastError string     `bson:"lastError,omitempty" json:"lastError,omitempty"`
+}
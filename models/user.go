@@ -6,13 +6,56 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User roles, ordered from least to most privileged
+const (
+	RoleStudent   = "student"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
 	FullName      string               `bson:"fullName" json:"fullName"`
 	Email         string               `bson:"email" json:"email"`
+	EmailVerified bool                 `bson:"emailVerified" json:"emailVerified"`
+	PendingEmail  string               `bson:"pendingEmail,omitempty" json:"pendingEmail,omitempty"` // new address awaiting confirmation
 	Password      string               `bson:"password,omitempty" json:"-"`
 	Bio           string               `bson:"bio,omitempty" json:"bio"`
+	Role          string               `bson:"role" json:"role"`
+	Reputation    int                  `bson:"reputation" json:"reputation"`
+	TOTPEnabled   bool                 `bson:"totpEnabled" json:"totpEnabled"`
+	TOTPSecret    string               `bson:"totpSecret,omitempty" json:"-"`
+	TOTPPending   string               `bson:"totpPending,omitempty" json:"-"` // secret awaiting confirmation
+	TOTPLastStep  int64                `bson:"totpLastStep,omitempty" json:"-"`
+	RecoveryCodes []string             `bson:"recoveryCodes,omitempty" json:"-"` // hashed
+	Identities    []Identity           `bson:"identities,omitempty" json:"identities,omitempty"`
 	Contributions []primitive.ObjectID `bson:"contributions" json:"contributions"`
 	CreatedAt     time.Time            `bson:"createdAt" json:"createdAt"`
 	UpdatedAt     time.Time            `bson:"updatedAt" json:"updatedAt"`
 }
+
+// Identity links a user to an account at an external login provider
+type Identity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"-"`
+	Email    string    `bson:"email" json:"email"`
+	LinkedAt time.Time `bson:"linkedAt" json:"linkedAt"`
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	return role == RoleStudent || role == RoleModerator || role == RoleAdmin
+}
+
+// NormalizeRole maps unknown or missing roles (older accounts) to RoleStudent
+func NormalizeRole(role string) string {
+	if IsValidRole(role) {
+		return role
+	}
+	return RoleStudent
+}
+
+// IsModeratorRole reports whether the role may act on other users' content
+func IsModeratorRole(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
+}
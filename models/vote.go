@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of content that can be voted on
+const (
+	VoteTargetQuestion = "question"
+	VoteTargetAnswer   = "answer"
+	VoteTargetComment  = "comment"
+)
+
+// Vote is one user's vote on a question, answer or comment. Direction is 1 for an
+// upvote and -1 for a downvote; retracted votes are deleted.
+type Vote struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	TargetID   primitive.ObjectID `bson:"targetId" json:"targetId"`
+	TargetType string             `bson:"targetType" json:"targetType"`
+	Direction  int                `bson:"direction" json:"direction"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/tr-choudhury21/prepportal_backend/controllers"
 	"github.com/tr-choudhury21/prepportal_backend/middleware"
+	"github.com/tr-choudhury21/prepportal_backend/models"
 )
 
 func AuthRoutes(router *gin.Engine) {
@@ -12,8 +13,26 @@ func AuthRoutes(router *gin.Engine) {
 	{
 		auth.POST("/register", controllers.Signup)
 		auth.POST("/login", controllers.Login)
+		auth.POST("/login/2fa", controllers.VerifyTwoFactor)
+		auth.GET("/oidc/:provider/login", controllers.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", controllers.OIDCCallback)
+		auth.POST("/oidc/:provider/link", middleware.AuthMiddleware(), controllers.OIDCLink)
+		auth.POST("/refresh", controllers.RefreshToken)
+		auth.POST("/logout", middleware.AuthMiddleware(), controllers.Logout)
+		auth.POST("/forgot-password", controllers.ForgotPassword)
+		auth.POST("/reset-password", controllers.ResetPassword)
+		auth.GET("/verify", controllers.VerifyEmail)
+		auth.POST("/verify/resend", middleware.AuthMiddleware(), controllers.ResendVerification)
 		auth.GET("/profile", middleware.AuthMiddleware(), controllers.GetUserProfile)
 		auth.PUT("/profile", middleware.AuthMiddleware(), controllers.UpdateUserProfile)
+		auth.PUT("/password", middleware.AuthMiddleware(), controllers.ChangePassword)
+		auth.PUT("/email", middleware.AuthMiddleware(), controllers.ChangeEmail)
+		auth.GET("/email/confirm", controllers.ConfirmEmailChange)
+		auth.POST("/2fa/enroll", middleware.AuthMiddleware(), controllers.EnrollTwoFactor)
+		auth.POST("/2fa/confirm", middleware.AuthMiddleware(), controllers.ConfirmTwoFactor)
+		auth.POST("/2fa/recovery-codes", middleware.AuthMiddleware(), controllers.RegenerateRecoveryCodes)
+		auth.POST("/2fa/disable", middleware.AuthMiddleware(), controllers.DisableTwoFactor)
 		auth.GET("/leaderboard", controllers.GetLeaderboard)
+		auth.PUT("/users/:id/role", middleware.AuthMiddleware(), middleware.RequireRole(models.RoleAdmin), controllers.UpdateUserRole)
 	}
 }
@@ -10,9 +10,10 @@ func BlogRoutes(router *gin.Engine) {
 	blogGroup := router.Group("/blogs")
 	{
 		blogGroup.GET("/", controllers.GetAllBlogs)
-		blogGroup.GET("/:id", controllers.GetBlog)
-		blogGroup.POST("/", middleware.AuthMiddleware(), controllers.CreateBlog)
+		blogGroup.GET("/:id", middleware.OptionalAuth(), controllers.GetBlog)
+		blogGroup.POST("/", middleware.AuthMiddleware(), middleware.RequireVerifiedEmail(), controllers.CreateBlog)
 		blogGroup.PUT("/:id", middleware.AuthMiddleware(), controllers.UpdateBlog)
 		blogGroup.DELETE("/:id", middleware.AuthMiddleware(), controllers.DeleteBlog)
+		blogGroup.POST("/:id/report", middleware.AuthMiddleware(), middleware.RequireVerifiedEmail(), controllers.ReportBlog)
 	}
 }
@@ -9,9 +9,11 @@ import (
 func DocumentRoutes(router *gin.Engine) {
 	docs := router.Group("/documents")
 	{
-		docs.POST("/", middleware.AuthMiddleware(), controllers.CreateDocument) // Protected
+		docs.POST("/", middleware.AuthMiddleware(), middleware.RequireVerifiedEmail(), controllers.CreateDocument) // Protected
 		docs.GET("/", controllers.GetAllDocuments)
-		docs.GET("/:branch", controllers.GetDocumentsByBranch)
+		docs.GET("/branch/:branch", controllers.GetDocumentsByBranch)
+		docs.GET("/:id", controllers.GetDocument)
+		docs.GET("/:id/download", controllers.DownloadDocument)
 		docs.PUT("/:id", middleware.AuthMiddleware(), controllers.UpdateDocument)    // Protected
 		docs.DELETE("/:id", middleware.AuthMiddleware(), controllers.DeleteDocument) // Protected
 	}
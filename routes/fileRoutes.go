@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/controllers"
+)
+
+// FileRoutes serves uploads when they are stored on the local disk
+func FileRoutes(router *gin.Engine) {
+	router.GET("/files/*key", controllers.ServeLocalFile)
+}
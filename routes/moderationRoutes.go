@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/controllers"
+	"github.com/tr-choudhury21/prepportal_backend/middleware"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+)
+
+func ModerationRoutes(router *gin.Engine) {
+	moderation := router.Group("/moderation", middleware.AuthMiddleware(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+	{
+		moderation.GET("/queue", controllers.GetModerationQueue)
+		moderation.POST("/:type/:id/:action", controllers.ModerateContent)
+	}
+}
@@ -4,18 +4,39 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/tr-choudhury21/prepportal_backend/controllers"
 	"github.com/tr-choudhury21/prepportal_backend/middleware"
+	"github.com/tr-choudhury21/prepportal_backend/models"
 )
 
 func QnaRoutes(router *gin.Engine) {
 	qnaGroup := router.Group("/qna")
 	{
-		qnaGroup.POST("/ask", middleware.AuthMiddleware(), controllers.AskQuestion)
+		qnaGroup.POST("/ask", middleware.AuthMiddleware(), middleware.RequireVerifiedEmail(), controllers.AskQuestion)
 		qnaGroup.POST("/answer/:id", middleware.AuthMiddleware(), controllers.AnswerQuestion)
-		qnaGroup.GET("/all", controllers.GetPaginatedQnA)
+		qnaGroup.GET("/all", middleware.OptionalAuth(), controllers.GetPaginatedQnA)
+		qnaGroup.GET("/tags", controllers.GetQnaTags)
+		qnaGroup.GET("/similar", controllers.SuggestDuplicates)
+		qnaGroup.GET("/:id", middleware.OptionalAuth(), controllers.GetQuestion)
+		qnaGroup.GET("/:id/answers", middleware.OptionalAuth(), controllers.GetAnswers)
 		//qnaGroup.GET("/paginated", controllers.GetPaginatedQnA)
 		qnaGroup.POST("/vote/:id", middleware.AuthMiddleware(), controllers.VoteQuestion)
 		qnaGroup.POST("/answer/vote/:id", middleware.AuthMiddleware(), controllers.VoteAnswer)
-		qnaGroup.POST("/report/:id", middleware.AuthMiddleware(), controllers.ReportQuestion)
+		qnaGroup.POST("/report/:id", middleware.AuthMiddleware(), middleware.RequireVerifiedEmail(), controllers.ReportQuestion)
+		qnaGroup.POST("/answer/report/:id", middleware.AuthMiddleware(), middleware.RequireVerifiedEmail(), controllers.ReportAnswer)
+		qnaGroup.POST("/accept/:id", middleware.AuthMiddleware(), controllers.AcceptAnswer)
+		qnaGroup.DELETE("/accept/:id", middleware.AuthMiddleware(), controllers.UnacceptAnswer)
+		qnaGroup.PUT("/:id", middleware.AuthMiddleware(), controllers.EditQuestion)
+		qnaGroup.DELETE("/:id", middleware.AuthMiddleware(), controllers.DeleteQuestion)
+		qnaGroup.PUT("/answer/:id", middleware.AuthMiddleware(), controllers.EditAnswer)
+		qnaGroup.DELETE("/answer/:id", middleware.AuthMiddleware(), controllers.DeleteAnswer)
+		qnaGroup.POST("/answer/:id/comments", middleware.AuthMiddleware(), controllers.AddComment)
+		qnaGroup.GET("/answer/:id/comments", middleware.OptionalAuth(), controllers.GetComments)
+		qnaGroup.GET("/comment/:id/replies", middleware.OptionalAuth(), controllers.GetCommentReplies)
+		qnaGroup.PUT("/comment/:id", middleware.AuthMiddleware(), controllers.EditComment)
+		qnaGroup.DELETE("/comment/:id", middleware.AuthMiddleware(), controllers.DeleteComment)
+		qnaGroup.POST("/comment/vote/:id", middleware.AuthMiddleware(), controllers.VoteComment)
+		qnaGroup.POST("/:id/duplicate", middleware.AuthMiddleware(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), controllers.CloseAsDuplicate)
+		qnaGroup.DELETE("/:id/duplicate", middleware.AuthMiddleware(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), controllers.ReopenQuestion)
+		qnaGroup.GET("/revisions/:id", middleware.AuthMiddleware(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin), controllers.GetRevisions)
 
 	}
 }
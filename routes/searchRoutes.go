@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/controllers"
+)
+
+func SearchRoutes(router *gin.Engine) {
+	router.GET("/search", controllers.Search)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tr-choudhury21/prepportal_backend/controllers"
+)
+
+func WellKnownRoutes(router *gin.Engine) {
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", controllers.GetJWKS)
+	}
+}
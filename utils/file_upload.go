@@ -4,48 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"mime"
 	"mime/multipart"
-
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
-	"github.com/tr-choudhury21/prepportal_backend/config"
+	"path"
 )
 
-func UploadFile(file multipart.File, fileName string) (string, error) {
-
-	if config.CLD == nil {
-		return "", errors.New("cloudinary is not initialized")
-	}
-
-	ctx := context.Background()
-
-	// Upload the file to Cloudinary
-	uploadResult, err := config.CLD.Upload.Upload(ctx, file, uploader.UploadParams{
-		PublicID: fileName,
-		Folder:   "documents",
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %v", err)
-	}
+// StoredFile is an uploaded file: Key identifies it in the storage backend and
+// URL is where it is served from
+type StoredFile struct {
+	Key string
+	URL string
+}
 
-	return uploadResult.SecureURL, nil
+func UploadFile(file multipart.File, fileName string) (StoredFile, error) {
+	return upload(file, "documents", fileName, mime.TypeByExtension(path.Ext(fileName)))
 }
 
-// UploadImage uploads an image file to Cloudinary and returns the URL
-func UploadImage(file multipart.File, fileHeader *multipart.FileHeader) (string, error) {
+// UploadImage uploads a blog image and returns where it is stored
+func UploadImage(file multipart.File, fileHeader *multipart.FileHeader) (StoredFile, error) {
+	return upload(file, "blog_images", fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+}
 
-	if config.CLD == nil {
-		return "", errors.New("cloudinary is not initialized")
+func upload(file multipart.File, folder, fileName, contentType string) (StoredFile, error) {
+	s := GetStorage()
+	if s == nil {
+		return StoredFile{}, errors.New("storage is not initialized")
 	}
 
-	ctx := context.Background()
-
-	// Upload to Cloudinary
-	resp, err := config.CLD.Upload.Upload(ctx, file, uploader.UploadParams{
-		Folder: "blog_images", // Store images in a separate folder
-	})
+	key := NewStorageKey(folder, fileName)
+	url, err := s.Put(context.Background(), key, file, contentType)
 	if err != nil {
-		return "", fmt.Errorf("error uploading image: %v", err)
+		return StoredFile{}, fmt.Errorf("failed to upload file: %v", err)
 	}
 
-	return resp.SecureURL, nil
+	return StoredFile{Key: key, URL: url}, nil
 }
@@ -5,43 +5,140 @@ import (
 	"os"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tr-choudhury21/prepportal_backend/models"
 )
 
 type Claims struct {
-	Email    string `json:"email"`
-	FullName string `json:"fullName"`
-	UserID   string `json:"userID"`
-	jwt.StandardClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	FullName      string `json:"fullName"`
+	UserID        string `json:"userID"`
+	Role          string `json:"role"`
+	SessionID     string `json:"sid"`
+	MFA           bool   `json:"mfa"`
+	jwt.RegisteredClaims
 }
 
-func GenerateToken(email, fullName, userID string) (string, error) {
+// challengeAudience keeps two-factor challenge tokens from being used as access tokens
+const challengeAudience = "prepportal-2fa"
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+const challengeTokenTTL = 5 * time.Minute
+
+// accessTokenAudience is the audience of tokens accepted by AuthMiddleware
+func accessTokenAudience() string {
+	if aud := os.Getenv("JWT_AUDIENCE"); aud != "" {
+		return aud
+	}
+	return "prepportal-api"
+}
+
+func tokenIssuer() string {
+	if iss := os.Getenv("JWT_ISSUER"); iss != "" {
+		return iss
+	}
+	return "prepportal"
+}
+
+// GenerateToken issues a short-lived access token bound to the given session
+func GenerateToken(user models.User, session *models.Session) (string, error) {
+
+	ring, err := getKeyRing()
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute))
 	claims := &Claims{
-		Email:    email,
-		FullName: fullName,
-		UserID:   userID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		FullName:      user.FullName,
+		UserID:        user.ID.Hex(),
+		Role:          models.NormalizeRole(user.Role),
+		SessionID:     session.ID.Hex(),
+		MFA:           session.MFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    tokenIssuer(),
+			Subject:   user.ID.Hex(),
+			Audience:  jwt.ClaimStrings{accessTokenAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return ring.Sign(claims)
+}
+
+// parseToken verifies signature, algorithm, issuer, audience and expiry of a token
+func parseToken(tokenString, audience string, claims jwt.Claims) error {
+	ring, err := getKeyRing()
+	if err != nil {
+		return err
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, ring.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(tokenIssuer()),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil || !token.Valid {
+		return errors.New("invalid or expired token")
+	}
 
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return nil
 }
 
 // Validate JWT Token
 func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil || !token.Valid {
-		return nil, errors.New("invalid or expired token")
+	if err := parseToken(tokenString, accessTokenAudience(), claims); err != nil {
+		return nil, err
 	}
 
 	return claims, nil
 }
+
+// GenerateChallengeToken issues the short-lived token a user exchanges, together
+// with a TOTP code, for real tokens once their password has been checked
+func GenerateChallengeToken(userID string) (string, error) {
+	ring, err := getKeyRing()
+	if err != nil {
+		return "", err
+	}
+
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &jwt.RegisteredClaims{
+		ID:        jti,
+		Issuer:    tokenIssuer(),
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(challengeTokenTTL)),
+	}
+
+	return ring.Sign(claims)
+}
+
+// ValidateChallengeToken returns the user ID a challenge token was issued for
+func ValidateChallengeToken(tokenString string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := parseToken(tokenString, challengeAudience, claims); err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one entry of the key ring. Retired keys only have a public half.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeyRing holds the active signing key plus every key still accepted for verification,
+// so tokens signed before a rotation stay valid until they expire.
+type KeyRing struct {
+	mu     sync.RWMutex
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var (
+	keyRing   *KeyRing
+	keyRingMu sync.RWMutex
+)
+
+// InitKeyRing loads the JWT keys described by the environment:
+//
+//	JWT_KEYS_DIR    directory of <kid>.pem files (PKCS#8/PKCS#1 private keys or PKIX public keys)
+//	JWT_ACTIVE_KID  kid of the private key used to sign new tokens
+//
+// Only RSA (RS256) and Ed25519 (EdDSA) keys are accepted. Without JWT_KEYS_DIR an
+// ephemeral Ed25519 key is generated, which is only suitable for development.
+func InitKeyRing() {
+	ring, err := LoadKeyRing(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_ACTIVE_KID"))
+	if err != nil {
+		log.Fatal("Failed to load JWT keys: ", err)
+	}
+
+	SetKeyRing(ring)
+	log.Println("✅ JWT key ring loaded, signing with kid", ring.active.kid)
+}
+
+// SetKeyRing replaces the key ring used to sign and verify tokens
+func SetKeyRing(ring *KeyRing) {
+	keyRingMu.Lock()
+	defer keyRingMu.Unlock()
+	keyRing = ring
+}
+
+func getKeyRing() (*KeyRing, error) {
+	keyRingMu.RLock()
+	defer keyRingMu.RUnlock()
+
+	if keyRing == nil {
+		return nil, errors.New("jwt key ring is not initialized")
+	}
+	return keyRing, nil
+}
+
+// LoadKeyRing reads every key in dir and signs with activeKID
+func LoadKeyRing(dir, activeKID string) (*KeyRing, error) {
+	if dir == "" {
+		log.Println("⚠️ JWT_KEYS_DIR not set, generating an ephemeral signing key")
+		return NewEphemeralKeyRing()
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	ring := &KeyRing{keys: map[string]*signingKey{}}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parseKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		ring.keys[kid] = key
+	}
+
+	active, ok := ring.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", activeKID, dir)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeKID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// NewEphemeralKeyRing creates a ring with a single freshly generated Ed25519 key
+func NewEphemeralKeyRing() (*KeyRing, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := GenerateRandomToken(8)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: private, public: public}
+	return &KeyRing{active: key, keys: map[string]*signingKey{kid: key}}, nil
+}
+
+func parseKeyPEM(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
+
+// Sign signs the claims with the active key and sets the kid header
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+
+	return token.SignedString(active.private)
+}
+
+// keyFunc resolves the verification key from the kid header and pins the algorithm to it
+func (k *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+
+	return key.public, nil
+}
+
+// JWKS returns the public keys as a JSON Web Key Set
+func (k *KeyRing) JWKS() map[string]interface{} {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := []map[string]string{}
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := map[string]string{"kid": kid, "alg": key.method.Alg(), "use": "sig"}
+
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		keys = append(keys, jwk)
+	}
+
+	return map[string]interface{}{"keys": keys}
+}
+
+// JWKS returns the public key set of the active key ring
+func JWKS() (map[string]interface{}, error) {
+	ring, err := getKeyRing()
+	if err != nil {
+		return nil, err
+	}
+	return ring.JWKS(), nil
+}
@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AttemptRecord is the login history of one key (an account or an IP). Every
+// attempt counts as a failure until it succeeds.
+type AttemptRecord struct {
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"lastFailure"`
+}
+
+// AttemptStore keeps login attempt counters. Counters restart once a key has
+// had no attempt for the given window.
+type AttemptStore interface {
+	Get(ctx context.Context, key string) (AttemptRecord, error)
+	// Reserve counts one more attempt if the key's record is still prev, and
+	// reports whether it did. Concurrent callers cannot both reserve from prev.
+	Reserve(ctx context.Context, key string, prev AttemptRecord, window time.Duration) (bool, error)
+	// Release takes back one reserved attempt
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+// MemoryAttemptStore is an in-process AttemptStore, fine for a single instance
+type MemoryAttemptStore struct {
+	mu      sync.Mutex
+	records map[string]memoryAttempt
+}
+
+type memoryAttempt struct {
+	AttemptRecord
+	expiresAt time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{records: map[string]memoryAttempt{}}
+}
+
+// current returns the unexpired record for key; s.mu must be held
+func (s *MemoryAttemptStore) current(key string, now time.Time) AttemptRecord {
+	rec, ok := s.records[key]
+	if !ok || now.After(rec.expiresAt) {
+		return AttemptRecord{}
+	}
+	return rec.AttemptRecord
+}
+
+func (s *MemoryAttemptStore) Get(ctx context.Context, key string) (AttemptRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current(key, time.Now()), nil
+}
+
+func (s *MemoryAttemptStore) Reserve(ctx context.Context, key string, prev AttemptRecord, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.current(key, now) != prev {
+		return false, nil
+	}
+
+	s.records[key] = memoryAttempt{
+		AttemptRecord: AttemptRecord{Failures: prev.Failures + 1, LastFailure: now},
+		expiresAt:     now.Add(window),
+	}
+
+	// Drop stale keys now and then so the map cannot grow forever
+	if len(s.records) > 10000 {
+		for k, r := range s.records {
+			if now.After(r.expiresAt) {
+				delete(s.records, k)
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func (s *MemoryAttemptStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok && rec.Failures > 0 {
+		rec.Failures--
+		s.records[key] = rec
+	}
+	return nil
+}
+
+func (s *MemoryAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// MongoAttemptStore shares counters between instances through the login_attempts collection
+type MongoAttemptStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAttemptStore(collection *mongo.Collection) *MongoAttemptStore {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, _ = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+
+	return &MongoAttemptStore{collection: collection}
+}
+
+func (s *MongoAttemptStore) Get(ctx context.Context, key string) (AttemptRecord, error) {
+	var rec AttemptRecord
+	err := s.collection.FindOne(ctx, bson.M{"_id": key, "expiresAt": bson.M{"$gt": time.Now()}}).Decode(&rec)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return AttemptRecord{}, nil
+	}
+	return rec, err
+}
+
+func (s *MongoAttemptStore) Reserve(ctx context.Context, key string, prev AttemptRecord, window time.Duration) (bool, error) {
+	now := time.Now()
+
+	// Only update the record prev was read from; an empty prev is a missing
+	// or expired record, which the upsert creates
+	filter := bson.M{"_id": key, "expiresAt": bson.M{"$lte": now}}
+	if prev.Failures > 0 {
+		filter = bson.M{"_id": key, "failures": prev.Failures, "lastFailure": prev.LastFailure, "expiresAt": bson.M{"$gt": now}}
+	}
+	update := bson.M{"$set": bson.M{"failures": prev.Failures + 1, "lastFailure": now, "expiresAt": now.Add(window)}}
+
+	result, err := s.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(prev.Failures == 0))
+	if mongo.IsDuplicateKeyError(err) {
+		// another attempt created the record first
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount+result.UpsertedCount > 0, nil
+}
+
+func (s *MongoAttemptStore) Release(ctx context.Context, key string) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": key, "failures": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"failures": -1}},
+	)
+	return err
+}
+
+func (s *MongoAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// ThrottlePolicy allows FreeAttempts failures, then doubles the wait from BaseDelay
+// on every further failure, up to MaxDelay (the lockout).
+type ThrottlePolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+}
+
+// Delay is how long a key with the given number of failures must wait after its last one
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if failures < p.FreeAttempts {
+		return 0
+	}
+
+	exp := float64(failures - p.FreeAttempts)
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, exp))
+	if delay <= 0 || delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// LoginThrottle applies separate policies to accounts and client IPs
+type LoginThrottle struct {
+	Store         AttemptStore
+	AccountPolicy ThrottlePolicy
+	IPPolicy      ThrottlePolicy
+	Window        time.Duration
+}
+
+var loginThrottle = &LoginThrottle{
+	Store:         NewMemoryAttemptStore(),
+	AccountPolicy: ThrottlePolicy{FreeAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute},
+	IPPolicy:      ThrottlePolicy{FreeAttempts: 20, BaseDelay: 30 * time.Second, MaxDelay: time.Hour},
+	Window:        time.Hour,
+}
+
+// InitLoginThrottle picks the attempt store from LOGIN_THROTTLE_STORE ("mongo" or "memory", the default)
+func InitLoginThrottle() {
+	if os.Getenv("LOGIN_THROTTLE_STORE") == "mongo" {
+		loginThrottle.Store = NewMongoAttemptStore(config.GetCollection("login_attempts"))
+		log.Println("✅ Login throttling uses MongoDB")
+		return
+	}
+	loginThrottle.Store = NewMemoryAttemptStore()
+}
+
+// GetLoginThrottle returns the shared login throttle
+func GetLoginThrottle() *LoginThrottle {
+	return loginThrottle
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func retryAfter(rec AttemptRecord, policy ThrottlePolicy) time.Duration {
+	if rec.Failures == 0 {
+		return 0
+	}
+	wait := time.Until(rec.LastFailure.Add(policy.Delay(rec.Failures)))
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// reserve counts an attempt against key unless it must wait, and returns the wait
+func (t *LoginThrottle) reserve(ctx context.Context, key string, policy ThrottlePolicy) (time.Duration, error) {
+	for {
+		rec, err := t.Store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if wait := retryAfter(rec, policy); wait > 0 {
+			return wait, nil
+		}
+
+		reserved, err := t.Store.Reserve(ctx, key, rec, t.Window)
+		if err != nil || reserved {
+			return 0, err
+		}
+		// a concurrent attempt changed the record, decide again
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Attempt reserves a login attempt for the account and the IP before the
+// password is checked. It counts as a failure until Success is called, so
+// parallel attempts cannot all get past the backoff. A positive wait means
+// the attempt is refused and nothing was counted.
+func (t *LoginThrottle) Attempt(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, err := t.reserve(ctx, ipKey(ip), t.IPPolicy)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+
+	wait, err = t.reserve(ctx, accountKey(email), t.AccountPolicy)
+	if err != nil || wait > 0 {
+		if err := t.Store.Release(ctx, ipKey(ip)); err != nil {
+			log.Println("⚠️ Failed to release login attempt:", err)
+		}
+	}
+	return wait, err
+}
+
+// Success clears the account counter and takes back the IP's attempt. The rest
+// of the IP counter is left alone so one valid account cannot be used to reset
+// an IP that is guessing other accounts.
+func (t *LoginThrottle) Success(ctx context.Context, email, ip string) error {
+	if err := t.Store.Reset(ctx, accountKey(email)); err != nil {
+		return err
+	}
+	return t.Store.Release(ctx, ipKey(ip))
+}
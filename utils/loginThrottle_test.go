@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyDelay(t *testing.T) {
+	policy := ThrottlePolicy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for _, tc := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second}, // 16s is capped
+		{200, 10 * time.Second},
+	} {
+		if got := policy.Delay(tc.failures); got != tc.want {
+			t.Errorf("Delay(%d) = %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestMemoryAttemptStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAttemptStore()
+
+	rec, _ := store.Get(ctx, "k")
+	if rec != (AttemptRecord{}) {
+		t.Fatalf("new key = %+v, want an empty record", rec)
+	}
+
+	if ok, _ := store.Reserve(ctx, "k", rec, time.Hour); !ok {
+		t.Fatal("first reservation failed")
+	}
+	// a second caller still holding the old record loses
+	if ok, _ := store.Reserve(ctx, "k", rec, time.Hour); ok {
+		t.Fatal("reservation from a stale record succeeded")
+	}
+
+	rec, _ = store.Get(ctx, "k")
+	if rec.Failures != 1 || rec.LastFailure.IsZero() {
+		t.Fatalf("after one reservation = %+v", rec)
+	}
+	if ok, _ := store.Reserve(ctx, "k", rec, time.Hour); !ok {
+		t.Fatal("reservation from the current record failed")
+	}
+
+	store.Release(ctx, "k")
+	if rec, _ = store.Get(ctx, "k"); rec.Failures != 1 {
+		t.Fatalf("after release failures = %d, want 1", rec.Failures)
+	}
+
+	store.Reset(ctx, "k")
+	if rec, _ = store.Get(ctx, "k"); rec.Failures != 0 {
+		t.Fatalf("after reset failures = %d, want 0", rec.Failures)
+	}
+
+	// counters restart once the window passes without attempts
+	store.Reserve(ctx, "short", AttemptRecord{}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if rec, _ = store.Get(ctx, "short"); rec.Failures != 0 {
+		t.Fatalf("expired key failures = %d, want 0", rec.Failures)
+	}
+	if ok, _ := store.Reserve(ctx, "short", AttemptRecord{}, time.Hour); !ok {
+		t.Fatal("reservation over an expired record failed")
+	}
+}
+
+func TestLoginThrottleParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	throttle := &LoginThrottle{
+		Store:         NewMemoryAttemptStore(),
+		AccountPolicy: ThrottlePolicy{FreeAttempts: 5, BaseDelay: time.Minute, MaxDelay: time.Hour},
+		IPPolicy:      ThrottlePolicy{FreeAttempts: 1000, BaseDelay: time.Minute, MaxDelay: time.Hour},
+		Window:        time.Hour,
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := throttle.Attempt(ctx, "ada@example.com", "203.0.113.7")
+			if err != nil {
+				t.Error(err)
+			}
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 5 {
+		t.Fatalf("%d parallel attempts were allowed, want 5", allowed)
+	}
+
+	// refused attempts are not charged to the IP
+	if ip, _ := throttle.Store.Get(ctx, ipKey("203.0.113.7")); ip.Failures != 5 {
+		t.Fatalf("IP failures = %d, want 5", ip.Failures)
+	}
+
+	if err := throttle.Success(ctx, "ada@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := throttle.Attempt(ctx, "ada@example.com", "203.0.113.7"); wait != 0 {
+		t.Fatalf("attempt after success waits %v", wait)
+	}
+	if ip, _ := throttle.Store.Get(ctx, ipKey("203.0.113.7")); ip.Failures != 5 {
+		t.Fatalf("IP failures after success and one more attempt = %d, want 5", ip.Failures)
+	}
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mailer delivers plain text emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends mail through an SMTP relay
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send mail: %v", err)
+	}
+	return nil
+}
+
+// MailMessage is an email captured by MemoryMailer
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+	SentAt  time.Time
+}
+
+// memoryMailerLimit is how many emails a MemoryMailer keeps, dropping the oldest
+const memoryMailerLimit = 100
+
+// MemoryMailer keeps the latest sent emails in memory instead of delivering
+// them, for tests and local development. Bodies hold live verification and
+// reset links, so they are never logged.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []MailMessage
+	Quiet    bool // don't log that a message was sent
+}
+
+func (m *MemoryMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) >= memoryMailerLimit {
+		m.messages = append(m.messages[:0], m.messages[1:]...)
+	}
+	m.messages = append(m.messages, MailMessage{To: to, Subject: subject, Body: body, SentAt: time.Now()})
+	if !m.Quiet {
+		log.Printf("📧 Mail to %s not delivered (in-memory mailer): %s", to, subject)
+	}
+	return nil
+}
+
+// Messages returns a copy of every email sent so far
+func (m *MemoryMailer) Messages() []MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]MailMessage(nil), m.messages...)
+}
+
+// Reset forgets all captured emails
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
+
+var (
+	mailer   Mailer = &MemoryMailer{}
+	mailerMu sync.RWMutex
+)
+
+// InitMailer picks the mailer from MAILER_DRIVER, which must be set: "smtp", or
+// "memory" to keep emails in memory without delivering them
+func InitMailer() {
+	switch driver := os.Getenv("MAILER_DRIVER"); driver {
+	case "smtp":
+	case "memory":
+		SetMailer(&MemoryMailer{})
+		log.Println("⚠️ Using in-memory mailer, emails are not delivered")
+		return
+	case "":
+		log.Fatal("MAILER_DRIVER is not set, use smtp (or memory to not deliver emails)")
+	default:
+		log.Fatal("Unknown MAILER_DRIVER: ", driver)
+	}
+
+	if os.Getenv("SMTP_HOST") == "" || os.Getenv("SMTP_FROM") == "" {
+		log.Fatal("SMTP_HOST and SMTP_FROM are required for the smtp mailer")
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	SetMailer(&SMTPMailer{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	})
+	log.Println("✅ SMTP mailer initialized successfully!")
+}
+
+// SetMailer replaces the mailer used by SendMail
+func SetMailer(m Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	mailer = m
+}
+
+// GetMailer returns the active mailer
+func GetMailer() Mailer {
+	mailerMu.RLock()
+	defer mailerMu.RUnlock()
+	return mailer
+}
+
+// SendMail sends an email through the active mailer
+func SendMail(to, subject, body string) error {
+	return GetMailer().Send(to, subject, body)
+}
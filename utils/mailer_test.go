@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMemoryMailerKeepsLatestMessages(t *testing.T) {
+	mailer := &MemoryMailer{Quiet: true}
+	for i := 0; i < memoryMailerLimit+5; i++ {
+		mailer.Send("ada@example.com", "message "+strconv.Itoa(i), "body")
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != memoryMailerLimit {
+		t.Fatalf("kept %d messages, want %d", len(messages), memoryMailerLimit)
+	}
+	if messages[0].Subject != "message 5" || messages[len(messages)-1].Subject != "message "+strconv.Itoa(memoryMailerLimit+4) {
+		t.Fatalf("kept %q to %q, want the latest", messages[0].Subject, messages[len(messages)-1].Subject)
+	}
+
+	mailer.Reset()
+	if len(mailer.Messages()) != 0 {
+		t.Fatal("Reset kept messages")
+	}
+}
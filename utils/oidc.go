@@ -0,0 +1,397 @@
+package utils
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const OIDCStateTTL = 10 * time.Minute
+
+var ErrInvalidOIDCState = errors.New("invalid or expired login state")
+
+// ExternalIdentity is what a provider tells us about the user who signed in
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCProvider is one configured "Sign in with ..." provider
+type OIDCProvider struct {
+	Name   string
+	OAuth2 oauth2.Config
+
+	verifier  *oidc.IDTokenVerifier // nil for plain OAuth2 providers (GitHub)
+	githubAPI string
+}
+
+// AuthCodeURL builds the provider redirect for an authorization-code + PKCE login
+func (p *OIDCProvider) AuthCodeURL(state, nonce, verifier string) string {
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.verifier != nil {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+	return p.OAuth2.AuthCodeURL(state, opts...)
+}
+
+// Exchange trades the authorization code for tokens and returns the verified identity
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*ExternalIdentity, error) {
+	token, err := p.OAuth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %v", err)
+	}
+
+	if p.verifier == nil {
+		return p.githubIdentity(ctx, token)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("provider did not return an id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %v", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"` // some providers send "true" as a string
+		Name          string      `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &ExternalIdentity{
+		Provider:      p.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+// githubIdentity reads the user and their primary email from the GitHub API,
+// since GitHub's OAuth2 login has no id_token
+func (p *OIDCProvider) githubIdentity(ctx context.Context, token *oauth2.Token) (*ExternalIdentity, error) {
+	client := p.OAuth2.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(client, p.githubAPI+"/user", &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(client, p.githubAPI+"/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{Provider: p.Name, Subject: fmt.Sprint(user.ID), Name: user.Name}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+		}
+	}
+
+	return identity, nil
+}
+
+func getJSON(client *http.Client, url string, out interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+var (
+	oidcProviders   = map[string]*OIDCProvider{}
+	oidcProvidersMu sync.RWMutex
+)
+
+// InitOIDCProviders configures the providers listed in OIDC_PROVIDERS (e.g. "google,github").
+// Each provider NAME reads OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+// OIDC_<NAME>_REDIRECT_URL and, for OpenID Connect providers, OIDC_<NAME>_ISSUER.
+// "github" without an issuer uses GitHub's OAuth2 endpoints and API, which
+// OIDC_GITHUB_AUTH_URL, OIDC_GITHUB_TOKEN_URL and OIDC_GITHUB_API_URL override.
+func InitOIDCProviders() {
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := NewOIDCProviderFromEnv(ctx, name)
+		cancel()
+		if err != nil {
+			log.Printf("⚠️ Skipping login provider %s: %v", name, err)
+			continue
+		}
+
+		RegisterOIDCProvider(provider)
+		log.Println("✅ Login provider configured:", name)
+	}
+}
+
+// NewOIDCProviderFromEnv builds a provider from its OIDC_<NAME>_* variables
+func NewOIDCProviderFromEnv(ctx context.Context, name string) (*OIDCProvider, error) {
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	env := func(key string) string { return os.Getenv(prefix + key) }
+
+	provider := &OIDCProvider{
+		Name: name,
+		OAuth2: oauth2.Config{
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			RedirectURL:  env("REDIRECT_URL"),
+		},
+	}
+	if provider.OAuth2.ClientID == "" || provider.OAuth2.RedirectURL == "" {
+		return nil, errors.New("client ID and redirect URL are required")
+	}
+
+	issuer := env("ISSUER")
+	if issuer == "" && name == "github" {
+		provider.OAuth2.Endpoint = github.Endpoint
+		if authURL := env("AUTH_URL"); authURL != "" { // GitHub Enterprise
+			provider.OAuth2.Endpoint.AuthURL = authURL
+		}
+		if tokenURL := env("TOKEN_URL"); tokenURL != "" {
+			provider.OAuth2.Endpoint.TokenURL = tokenURL
+		}
+		provider.OAuth2.Scopes = []string{"read:user", "user:email"}
+		provider.githubAPI = "https://api.github.com"
+		if api := env("API_URL"); api != "" {
+			provider.githubAPI = strings.TrimRight(api, "/")
+		}
+		return provider, nil
+	}
+	if issuer == "" {
+		return nil, errors.New("issuer is required")
+	}
+
+	discovered, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.OAuth2.Endpoint = discovered.Endpoint()
+	provider.OAuth2.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	provider.verifier = discovered.Verifier(&oidc.Config{ClientID: provider.OAuth2.ClientID})
+
+	return provider, nil
+}
+
+// RegisterOIDCProvider makes a provider available under its name
+func RegisterOIDCProvider(provider *OIDCProvider) {
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+	oidcProviders[provider.Name] = provider
+}
+
+// GetOIDCProvider looks up a configured provider
+func GetOIDCProvider(name string) (*OIDCProvider, bool) {
+	oidcProvidersMu.RLock()
+	defer oidcProvidersMu.RUnlock()
+	provider, ok := oidcProviders[name]
+	return provider, ok
+}
+
+// OIDCState is the server side half of an in-flight login, keyed by the state parameter.
+// LinkUserID is set when a signed-in user is linking the provider to their account.
+// BrowserHash is the hash of a secret kept in a cookie of the browser that started it.
+type OIDCState struct {
+	State        string    `bson:"_id"`
+	Provider     string    `bson:"provider"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	LinkUserID   string    `bson:"linkUserId,omitempty"`
+	BrowserHash  string    `bson:"browserHash"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
+
+// FromBrowser reports whether the browser secret is the one the login started with
+func (s *OIDCState) FromBrowser(secret string) bool {
+	return secret != "" && subtle.ConstantTimeCompare([]byte(HashToken(secret)), []byte(s.BrowserHash)) == 1
+}
+
+// OIDCStateStore keeps in-flight logins until the provider redirects back
+type OIDCStateStore interface {
+	Save(ctx context.Context, state *OIDCState) error
+	// Consume removes and returns an unexpired state for the provider, or ErrInvalidOIDCState
+	Consume(ctx context.Context, state, provider string) (*OIDCState, error)
+}
+
+// MemoryOIDCStateStore is an in-process OIDCStateStore, for a single instance and tests
+type MemoryOIDCStateStore struct {
+	mu     sync.Mutex
+	states map[string]OIDCState
+}
+
+func NewMemoryOIDCStateStore() *MemoryOIDCStateStore {
+	return &MemoryOIDCStateStore{states: map[string]OIDCState{}}
+}
+
+func (s *MemoryOIDCStateStore) Save(ctx context.Context, state *OIDCState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, pending := range s.states {
+		if now.After(pending.ExpiresAt) {
+			delete(s.states, key)
+		}
+	}
+	s.states[state.State] = *state
+	return nil
+}
+
+func (s *MemoryOIDCStateStore) Consume(ctx context.Context, state, provider string) (*OIDCState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, ok := s.states[state]
+	if !ok || pending.Provider != provider || time.Now().After(pending.ExpiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+	delete(s.states, state)
+	return &pending, nil
+}
+
+// MongoOIDCStateStore shares in-flight logins between instances through the oidc_states collection
+type MongoOIDCStateStore struct{}
+
+var (
+	oidcStateCollection *mongo.Collection
+	oidcStateOnce       sync.Once
+)
+
+func getOIDCStateCollection() *mongo.Collection {
+	oidcStateOnce.Do(func() {
+		oidcStateCollection = config.GetCollection("oidc_states")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = oidcStateCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+	})
+	return oidcStateCollection
+}
+
+func (MongoOIDCStateStore) Save(ctx context.Context, state *OIDCState) error {
+	_, err := getOIDCStateCollection().InsertOne(ctx, state)
+	return err
+}
+
+func (MongoOIDCStateStore) Consume(ctx context.Context, state, provider string) (*OIDCState, error) {
+	var s OIDCState
+	err := getOIDCStateCollection().FindOneAndDelete(ctx, bson.M{
+		"_id":       state,
+		"provider":  provider,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+var (
+	oidcStateStore   OIDCStateStore = MongoOIDCStateStore{}
+	oidcStateStoreMu sync.RWMutex
+)
+
+// SetOIDCStateStore replaces where in-flight logins are kept
+func SetOIDCStateStore(store OIDCStateStore) {
+	oidcStateStoreMu.Lock()
+	defer oidcStateStoreMu.Unlock()
+	oidcStateStore = store
+}
+
+func getOIDCStateStore() OIDCStateStore {
+	oidcStateStoreMu.RLock()
+	defer oidcStateStoreMu.RUnlock()
+	return oidcStateStore
+}
+
+// NewOIDCState creates and stores the state, nonce and PKCE verifier for a login,
+// bound to browserSecret. linkUserID is empty for a plain login.
+func NewOIDCState(ctx context.Context, provider, linkUserID, browserSecret string) (*OIDCState, error) {
+	state, err := GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &OIDCState{
+		State:        state,
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		LinkUserID:   linkUserID,
+		BrowserHash:  HashToken(browserSecret),
+		ExpiresAt:    time.Now().Add(OIDCStateTTL),
+	}
+	if err := getOIDCStateStore().Save(ctx, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ConsumeOIDCState removes and returns a pending login, so each state works only once
+func ConsumeOIDCState(ctx context.Context, state, provider string) (*OIDCState, error) {
+	return getOIDCStateStore().Consume(ctx, state, provider)
+}
@@ -0,0 +1,342 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider is a minimal OpenID Connect provider: discovery, JWKS and a
+// token endpoint that checks PKCE before issuing a signed id_token
+type mockProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key, codes: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		m.mu.Lock()
+		grant, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+
+		if !ok || pkceChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		writeJSON(w, map[string]interface{}{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize plays the user consenting at the provider: it reads the PKCE
+// challenge and nonce from the login URL and returns an authorization code
+func (m *mockProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("login URL has no S256 PKCE challenge: %s", authURL)
+	}
+
+	all := jwt.MapClaims{
+		"iss":   m.URL,
+		"aud":   q.Get("client_id"),
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	code, _ := GenerateRandomToken(16)
+	m.mu.Lock()
+	m.codes[code] = mockGrant{challenge: q.Get("code_challenge"), claims: all}
+	m.mu.Unlock()
+	return code
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestProvider(t *testing.T, m *mockProvider) *OIDCProvider {
+	t.Helper()
+
+	t.Setenv("OIDC_MOCK_CLIENT_ID", "prepportal")
+	t.Setenv("OIDC_MOCK_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_MOCK_REDIRECT_URL", "http://localhost/auth/oidc/mock/callback")
+	t.Setenv("OIDC_MOCK_ISSUER", m.URL)
+
+	provider, err := NewOIDCProviderFromEnv(context.Background(), "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetOIDCStateStore(NewMemoryOIDCStateStore())
+	t.Cleanup(func() { SetOIDCStateStore(MongoOIDCStateStore{}) })
+	return provider
+}
+
+// startLogin begins a login and returns its state and the provider URL
+func startLogin(t *testing.T, provider *OIDCProvider) (*OIDCState, string) {
+	t.Helper()
+
+	state, err := NewOIDCState(context.Background(), provider.Name, "", "browser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state, provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier)
+}
+
+func TestOIDCLoginRoundTrip(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+	ctx := context.Background()
+
+	started, authURL := startLogin(t, provider)
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("state"); got != started.State {
+		t.Fatalf("login URL state = %q, want %q", got, started.State)
+	}
+	if got := u.Query().Get("redirect_uri"); got != "http://localhost/auth/oidc/mock/callback" {
+		t.Fatalf("login URL redirect_uri = %q", got)
+	}
+
+	code := m.authorize(t, authURL, jwt.MapClaims{
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada",
+	})
+
+	if _, err := ConsumeOIDCState(ctx, started.State, "other"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("state accepted for another provider: %v", err)
+	}
+	state, err := ConsumeOIDCState(ctx, started.State, provider.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConsumeOIDCState(ctx, started.State, provider.Name); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("state accepted twice: %v", err)
+	}
+
+	identity, err := provider.Exchange(ctx, code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ExternalIdentity{Provider: "mock", Subject: "user-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada"}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestOIDCExpiredState(t *testing.T) {
+	store := NewMemoryOIDCStateStore()
+	store.Save(context.Background(), &OIDCState{State: "old", Provider: "mock", ExpiresAt: time.Now().Add(-time.Second)})
+
+	if _, err := store.Consume(context.Background(), "old", "mock"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("expired state accepted: %v", err)
+	}
+}
+
+func TestOIDCWrongCodeVerifier(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+
+	state, authURL := startLogin(t, provider)
+	code := m.authorize(t, authURL, jwt.MapClaims{"sub": "user-1", "email": "ada@example.com", "email_verified": true})
+
+	other, _ := startLogin(t, provider)
+	if _, err := provider.Exchange(context.Background(), code, other.CodeVerifier, state.Nonce); err == nil {
+		t.Fatal("code exchanged with another login's PKCE verifier")
+	}
+}
+
+func TestOIDCNonceMismatch(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+
+	state, authURL := startLogin(t, provider)
+	code := m.authorize(t, authURL, jwt.MapClaims{
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"nonce":          "replayed",
+	})
+
+	_, err := provider.Exchange(context.Background(), code, state.CodeVerifier, state.Nonce)
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("expected a nonce mismatch, got %v", err)
+	}
+}
+
+func TestOIDCEmailVerifiedClaim(t *testing.T) {
+	m := newMockProvider(t)
+	provider := newTestProvider(t, m)
+
+	for _, tc := range []struct {
+		claim interface{}
+		want  bool
+	}{
+		{true, true},
+		{"true", true},
+		{false, false},
+		{nil, false},
+	} {
+		state, authURL := startLogin(t, provider)
+		claims := jwt.MapClaims{"sub": "user-1", "email": "ada@example.com"}
+		if tc.claim != nil {
+			claims["email_verified"] = tc.claim
+		}
+		code := m.authorize(t, authURL, claims)
+
+		identity, err := provider.Exchange(context.Background(), code, state.CodeVerifier, state.Nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if identity.EmailVerified != tc.want {
+			t.Errorf("email_verified %v: EmailVerified = %v, want %v", tc.claim, identity.EmailVerified, tc.want)
+		}
+	}
+}
+
+func TestGitHubLogin(t *testing.T) {
+	var challenge string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != "gh-code" || pkceChallenge(r.PostForm.Get("code_verifier")) != challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"bad_verification_code"}`))
+			return
+		}
+		writeJSON(w, map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/api/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"id": 42, "login": "octocat", "name": ""})
+	})
+	mux.HandleFunc("/api/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, []map[string]interface{}{
+			{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": "octo@example.com", "primary": true, "verified": true},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Setenv("OIDC_GITHUB_CLIENT_ID", "prepportal")
+	t.Setenv("OIDC_GITHUB_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_GITHUB_REDIRECT_URL", "http://localhost/auth/oidc/github/callback")
+	t.Setenv("OIDC_GITHUB_AUTH_URL", server.URL+"/login/oauth/authorize")
+	t.Setenv("OIDC_GITHUB_TOKEN_URL", server.URL+"/login/oauth/access_token")
+	t.Setenv("OIDC_GITHUB_API_URL", server.URL+"/api")
+
+	provider, err := NewOIDCProviderFromEnv(context.Background(), "github")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := "github-verifier-0123456789-0123456789-0123456789"
+	u, _ := url.Parse(provider.AuthCodeURL("state", "nonce", verifier))
+	if !strings.HasPrefix(u.String(), server.URL+"/login/oauth/authorize") || u.Query().Get("nonce") != "" {
+		t.Fatalf("unexpected GitHub login URL %s", u)
+	}
+	challenge = u.Query().Get("code_challenge")
+
+	identity, err := provider.Exchange(context.Background(), "gh-code", verifier, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ExternalIdentity{Provider: "github", Subject: "42", Email: "octo@example.com", EmailVerified: true, Name: "octocat"}
+	if *identity != want {
+		t.Fatalf("identity = %+v, want %+v", *identity, want)
+	}
+
+	if _, err := provider.Exchange(context.Background(), "gh-code", "wrong-verifier-0123456789-0123456789-0123", ""); err == nil {
+		t.Fatal("code exchanged with the wrong PKCE verifier")
+	}
+}
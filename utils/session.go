@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// how many rotated refresh token hashes are remembered per session for reuse detection
+const maxPreviousHashes = 20
+
+var (
+	sessionCollection *mongo.Collection
+	sessionOnce       sync.Once
+)
+
+func getSessionCollection() *mongo.Collection {
+	sessionOnce.Do(func() {
+		sessionCollection = config.GetCollection("sessions")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		// Expired sessions are removed by Mongo itself
+		_, _ = sessionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+			{Keys: bson.D{{Key: "refreshTokenHash", Value: 1}}},
+			{Keys: bson.D{{Key: "previousHashes", Value: 1}}},
+			{Keys: bson.D{{Key: "userId", Value: 1}}},
+		})
+	})
+	return sessionCollection
+}
+
+// durationFromEnv reads a time.Duration from the environment, falling back to def
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+func refreshTokenTTL() time.Duration {
+	return durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// GenerateRandomToken returns a URL safe random string with n bytes of entropy
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken hashes an opaque token so only the digest is ever stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	clientIPKey     []byte
+	clientIPKeyOnce sync.Once
+)
+
+// HashClientIP pseudonymises an IP address with a keyed HMAC, so the stored value
+// cannot be reversed by hashing every IPv4 address. The key comes from IP_HASH_SECRET;
+// without it a random key is used and hashes change on every restart.
+func HashClientIP(ip string) string {
+	clientIPKeyOnce.Do(func() {
+		clientIPKey = []byte(os.Getenv("IP_HASH_SECRET"))
+		if len(clientIPKey) == 0 {
+			log.Println("⚠️ IP_HASH_SECRET not set, IP hashes will change on restart")
+			clientIPKey = make([]byte, 32)
+			if _, err := rand.Read(clientIPKey); err != nil {
+				log.Fatal("Failed to generate IP hash key:", err)
+			}
+		}
+	})
+
+	mac := hmac.New(sha256.New, clientIPKey)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateSession opens a new session for the user and returns it with its raw refresh token
+func CreateSession(ctx context.Context, userID primitive.ObjectID, userAgent, ip string, mfa bool) (*models.Session, string, error) {
+	refreshToken, err := GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:               primitive.NewObjectID(),
+		UserID:           userID,
+		RefreshTokenHash: HashToken(refreshToken),
+		PreviousHashes:   []string{},
+		UserAgent:        userAgent,
+		IP:               ip,
+		MFA:              mfa,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+	}
+
+	if _, err := getSessionCollection().InsertOne(ctx, session); err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// RotateSession exchanges a refresh token for a new one. Presenting an already
+// rotated token revokes every session of that user, since it means the token leaked.
+func RotateSession(ctx context.Context, refreshToken string) (*models.Session, string, error) {
+	sessions := getSessionCollection()
+	oldHash := HashToken(refreshToken)
+
+	newToken, err := GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"refreshTokenHash": oldHash,
+		"revokedAt":        bson.M{"$exists": false},
+		"expiresAt":        bson.M{"$gt": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"refreshTokenHash": HashToken(newToken), "lastUsedAt": now},
+		"$push": bson.M{"previousHashes": bson.M{
+			"$each":  []string{oldHash},
+			"$slice": -maxPreviousHashes,
+		}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var session models.Session
+	err = sessions.FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if err == nil {
+		return &session, newToken, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", err
+	}
+
+	// Not a current token, check whether it is a rotated one
+	var reused models.Session
+	if err := sessions.FindOne(ctx, bson.M{"previousHashes": oldHash}).Decode(&reused); err == nil {
+		_ = RevokeAllSessions(ctx, reused.UserID)
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	return nil, "", ErrInvalidRefreshToken
+}
+
+// RevokeSession revokes a single session
+func RevokeSession(ctx context.Context, sessionID primitive.ObjectID) error {
+	_, err := getSessionCollection().UpdateOne(ctx,
+		bson.M{"_id": sessionID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	return err
+}
+
+// RevokeAllSessions logs the user out on every device
+func RevokeAllSessions(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := getSessionCollection().UpdateMany(ctx,
+		bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	return err
+}
+
+// IsSessionActive reports whether the session exists and has not been revoked or expired
+func IsSessionActive(ctx context.Context, sessionID string) bool {
+	objID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false
+	}
+
+	count, err := getSessionCollection().CountDocuments(ctx, bson.M{
+		"_id":       objID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}, options.Count().SetLimit(1))
+
+	return err == nil && count > 0
+}
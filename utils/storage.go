@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+)
+
+// ErrFileNotFound is returned by Storage.Get when no file is stored under the key
+var ErrFileNotFound = errors.New("file not found")
+
+// Storage keeps uploaded files. Keys are slash separated paths such as
+// "documents/<id>-notes.pdf"; every backend maps them onto its own naming.
+//
+// Uploads are public on every backend, so there is deliberately no signed URL
+// method: only S3 could give links a real expiry. Expiring links were left out of
+// the scope of this abstraction until there is private content to serve.
+type Storage interface {
+	// Put stores the file under key and returns its public URL
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	// Get opens a stored file
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes a file. Deleting a file that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+	// KeyForURL returns the key of the file a URL from Put points to, for
+	// records saved before their key was stored
+	KeyForURL(fileURL string) (string, bool)
+	// List calls fn for every file whose key starts with prefix. Cloudinary
+	// does not keep extensions, so there a key may end in a different one.
+	List(ctx context.Context, prefix string, fn func(FileInfo) error) error
+}
+
+// FileInfo is a file found by Storage.List
+type FileInfo struct {
+	Key     string
+	ModTime time.Time
+}
+
+var (
+	storage   Storage
+	storageMu sync.RWMutex
+)
+
+// InitStorage picks the storage backend from STORAGE_DRIVER ("cloudinary", "s3" or
+// "local"). Without it, Cloudinary is used when it is configured and the local
+// disk otherwise.
+func InitStorage() {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = "local"
+		if config.CLD != nil {
+			driver = "cloudinary"
+		}
+	}
+
+	switch driver {
+	case "cloudinary":
+		if config.CLD == nil {
+			log.Fatal("STORAGE_DRIVER is cloudinary but Cloudinary is not configured")
+		}
+		SetStorage(&CloudinaryStorage{CLD: config.CLD})
+		log.Println("✅ Storing files in Cloudinary")
+
+	case "s3":
+		s3, err := NewS3StorageFromEnv()
+		if err != nil {
+			log.Fatal("Failed to initialize S3 storage: ", err)
+		}
+		SetStorage(s3)
+		log.Println("✅ Storing files in S3 bucket", s3.Bucket)
+
+	case "local":
+		local := NewLocalStorageFromEnv()
+		SetStorage(local)
+		log.Println("⚠️ Storing files on local disk in", local.Dir)
+
+	default:
+		log.Fatal("Unknown STORAGE_DRIVER: ", driver)
+	}
+}
+
+// SetStorage replaces the storage used for uploads
+func SetStorage(s Storage) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	storage = s
+}
+
+// GetStorage returns the active storage, or nil before InitStorage
+func GetStorage() Storage {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+	return storage
+}
+
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// NewStorageKey builds a unique key for a file uploaded into folder, keeping a
+// readable version of its original name
+func NewStorageKey(folder, fileName string) string {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	name := unsafeKeyChars.ReplaceAllString(path.Base(strings.ReplaceAll(fileName, "\\", "/")), "-")
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		name = "file"
+	}
+	if len(name) > 100 {
+		name = name[len(name)-100:]
+	}
+
+	return folder + "/" + hex.EncodeToString(id) + "-" + name
+}
+
+// cleanKey rejects keys that could escape the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key || strings.Contains(key, "\\") {
+		return "", errors.New("invalid storage key")
+	}
+	return cleaned, nil
+}
+
+// StoredFileKey returns key, or when a record predates stored keys the key
+// derived from the file's URL. It is empty when neither identifies a file.
+func StoredFileKey(key, fileURL string) string {
+	if key != "" || fileURL == "" {
+		return key
+	}
+	if s := GetStorage(); s != nil {
+		if key, ok := s.KeyForURL(fileURL); ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// keyUnderURL returns the key of a file served at baseURL + "/" + escaped key
+func keyUnderURL(baseURL, fileURL string) (string, bool) {
+	escaped, ok := strings.CutPrefix(fileURL, baseURL+"/")
+	if !ok {
+		return "", false
+	}
+	escaped, _, _ = strings.Cut(escaped, "?")
+
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", false
+	}
+	key, err = cleanKey(key)
+	return key, err == nil
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// Cloudinary keeps each file as one of these resource types, and the public ID
+// alone does not say which
+var cloudinaryAssetTypes = []api.AssetType{api.Image, api.File, api.Video}
+
+// The version segment of a delivery URL, e.g. "v1712345678"
+var cloudinaryVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// CloudinaryStorage stores files in Cloudinary. Uploads are public, so signed
+// URLs are the plain delivery URLs.
+type CloudinaryStorage struct {
+	CLD *cloudinary.Cloudinary
+}
+
+// publicID drops the extension, which Cloudinary adds back to delivery URLs
+func (s *CloudinaryStorage) publicID(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(key, path.Ext(key)), nil
+}
+
+func (s *CloudinaryStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	publicID, err := s.publicID(key)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := s.CLD.Upload.Upload(ctx, r, uploader.UploadParams{
+		PublicID:     publicID,
+		ResourceType: api.Auto,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %v", err)
+	}
+	if res.Error.Message != "" {
+		return "", fmt.Errorf("failed to upload file: %s", res.Error.Message)
+	}
+	return res.SecureURL, nil
+}
+
+// asset looks the file up under each resource type
+func (s *CloudinaryStorage) asset(ctx context.Context, key string) (*admin.AssetResult, error) {
+	publicID, err := s.publicID(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, assetType := range cloudinaryAssetTypes {
+		res, err := s.CLD.Admin.Asset(ctx, admin.AssetParams{AssetType: assetType, PublicID: publicID})
+		if err != nil {
+			return nil, err
+		}
+		if res.Error.Message == "" && res.SecureURL != "" {
+			return res, nil
+		}
+	}
+	return nil, ErrFileNotFound
+}
+
+func (s *CloudinaryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	asset, err := s.asset(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.SecureURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", asset.SecureURL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (s *CloudinaryStorage) Delete(ctx context.Context, key string) error {
+	publicID, err := s.publicID(key)
+	if err != nil {
+		return err
+	}
+
+	for _, assetType := range cloudinaryAssetTypes {
+		res, err := s.CLD.Upload.Destroy(ctx, uploader.DestroyParams{
+			PublicID:     publicID,
+			ResourceType: assetType.String(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete file: %v", err)
+		}
+		if res.Error.Message != "" {
+			return fmt.Errorf("failed to delete file: %s", res.Error.Message)
+		}
+		// "not found" means it is stored under another resource type, or already gone
+		if res.Result == "ok" {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *CloudinaryStorage) List(ctx context.Context, prefix string, fn func(FileInfo) error) error {
+	for _, assetType := range cloudinaryAssetTypes {
+		params := admin.AssetsParams{AssetType: assetType, DeliveryType: "upload", Prefix: prefix, MaxResults: 500}
+		for {
+			res, err := s.CLD.Admin.Assets(ctx, params)
+			if err != nil {
+				return fmt.Errorf("failed to list files: %v", err)
+			}
+			if res.Error.Message != "" {
+				return fmt.Errorf("failed to list files: %s", res.Error.Message)
+			}
+
+			for _, asset := range res.Assets {
+				// publicID strips the extension again. Raw files have no format,
+				// and the lone dot stops a dot in their name from being cut instead.
+				key := asset.PublicID + "." + asset.Format
+				if err := fn(FileInfo{Key: key, ModTime: asset.CreatedAt}); err != nil {
+					return err
+				}
+			}
+
+			if res.NextCursor == "" {
+				break
+			}
+			params.NextCursor = res.NextCursor
+		}
+	}
+	return nil
+}
+
+// KeyForURL reads the public ID from a delivery URL such as
+// https://res.cloudinary.com/<cloud>/image/upload/v123/documents/notes.pdf.
+// The format extension stays on the key, as publicID strips it again.
+func (s *CloudinaryStorage) KeyForURL(fileURL string) (string, bool) {
+	u, err := url.Parse(fileURL)
+	if err != nil || u.Host != "res.cloudinary.com" {
+		return "", false
+	}
+
+	// "", cloud name, resource type, "upload", then the optional version and public ID
+	parts := strings.Split(u.Path, "/")
+	if len(parts) < 5 || parts[1] != s.CLD.Config.Cloud.CloudName || parts[3] != "upload" {
+		return "", false
+	}
+	parts = parts[4:]
+	if cloudinaryVersion.MatchString(parts[0]) {
+		parts = parts[1:]
+	}
+
+	key, err := cleanKey(strings.Join(parts, "/"))
+	return key, err == nil
+}
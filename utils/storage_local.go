@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage keeps files in a directory on disk. They are served publicly by
+// the API itself from BaseURL (see controllers.ServeLocalFile), like Cloudinary
+// uploads.
+type LocalStorage struct {
+	Dir     string
+	BaseURL string // e.g. http://localhost:8080/files
+}
+
+// NewLocalStorageFromEnv configures local storage from LOCAL_STORAGE_DIR and
+// LOCAL_STORAGE_URL
+func NewLocalStorageFromEnv() *LocalStorage {
+	dir := os.Getenv("LOCAL_STORAGE_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+
+	baseURL := os.Getenv("LOCAL_STORAGE_URL")
+	if baseURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		baseURL = "http://localhost:" + port + "/files"
+	}
+
+	return &LocalStorage{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Path returns where the file for key lives on disk
+func (s *LocalStorage) Path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStorage) url(key string) string {
+	return s.BaseURL + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	p, err := s.Path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+
+	// write to a temporary file first so readers never see a partial upload
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return s.url(key), nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.Path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string, fn func(FileInfo) error) error {
+	// only walk the directory the prefix points into
+	root := filepath.Join(s.Dir, filepath.FromSlash(path.Dir(prefix)))
+
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(FileInfo{Key: key, ModTime: info.ModTime()})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStorage) KeyForURL(fileURL string) (string, bool) {
+	return keyUnderURL(s.BaseURL, fileURL)
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Storage stores files in a bucket of any S3-compatible service (AWS, MinIO,
+// R2, ...). The URLs returned by Put are saved and handed to clients, so the
+// bucket must be publicly readable at PublicURL, e.g. through a bucket policy
+// or a CDN.
+type S3Storage struct {
+	Client    *minio.Client
+	Bucket    string
+	PublicURL string // where the bucket is publicly readable
+}
+
+// NewS3StorageFromEnv connects to the bucket from S3_ENDPOINT, S3_ACCESS_KEY,
+// S3_SECRET_KEY, S3_BUCKET, S3_REGION, S3_USE_SSL and S3_PUBLIC_URL, creating
+// the bucket if it does not exist
+func NewS3StorageFromEnv() (*S3Storage, error) {
+	endpoint := os.Getenv("S3_ENDPOINT")
+	bucket := os.Getenv("S3_BUCKET")
+	if endpoint == "" || bucket == "" {
+		return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required")
+	}
+
+	// New buckets are private, so the plain endpoint URL of a file would not load
+	publicURL := strings.TrimRight(os.Getenv("S3_PUBLIC_URL"), "/")
+	if publicURL == "" {
+		return nil, errors.New("S3_PUBLIC_URL is required, set it to where the bucket is publicly readable")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY"), ""),
+		Secure: os.Getenv("S3_USE_SSL") != "false",
+		Region: os.Getenv("S3_REGION"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: os.Getenv("S3_REGION")}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3Storage{
+		Client:    client,
+		Bucket:    bucket,
+		PublicURL: publicURL,
+	}, nil
+}
+
+func (s *S3Storage) url(key string) string {
+	return s.PublicURL + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.Client.PutObject(ctx, s.Bucket, key, r, -1, minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return "", err
+	}
+	return s.url(key), nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy, Stat surfaces a missing object
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	return s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string, fn func(FileInfo) error) error {
+	// stops the listing when fn fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for obj := range s.Client.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(FileInfo{Key: obj.Key, ModTime: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Storage) KeyForURL(fileURL string) (string, bool) {
+	return keyUnderURL(s.PublicURL, fileURL)
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	for _, key := range []string{"documents/a.pdf", "blog_images/x/y.png", "a"} {
+		if got, err := cleanKey(key); err != nil || got != key {
+			t.Errorf("cleanKey(%q) = %q, %v, want the key back", key, got, err)
+		}
+	}
+
+	for _, key := range []string{
+		"",
+		"../etc/passwd",
+		"documents/../../etc/passwd",
+		"documents/../secret",
+		"/etc/passwd",
+		"documents//a.pdf",
+		"documents/./a.pdf",
+		"documents/",
+		`documents\..\..\secret`,
+		"..",
+	} {
+		if got, err := cleanKey(key); err == nil {
+			t.Errorf("cleanKey(%q) = %q, want an error", key, got)
+		}
+	}
+}
+
+var storageKeyPattern = regexp.MustCompile(`^documents/[0-9a-f]{16}-(.+)$`)
+
+func TestNewStorageKey(t *testing.T) {
+	for _, tc := range []struct {
+		fileName string
+		want     string
+	}{
+		{"notes.pdf", "notes.pdf"},
+		{"My Notes (final).pdf", "My-Notes-final-.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\ada\notes.pdf`, "notes.pdf"},
+		{"..", "file"},
+		{"", "file"},
+		{"ünïcode.txt", "n-code.txt"},
+		{strings.Repeat("a", 150) + ".pdf", strings.Repeat("a", 96) + ".pdf"},
+	} {
+		key := NewStorageKey("documents", tc.fileName)
+		match := storageKeyPattern.FindStringSubmatch(key)
+		if match == nil {
+			t.Errorf("NewStorageKey(%q) = %q, want documents/<id>-<name>", tc.fileName, key)
+			continue
+		}
+		if match[1] != tc.want {
+			t.Errorf("NewStorageKey(%q) name = %q, want %q", tc.fileName, match[1], tc.want)
+		}
+		if _, err := cleanKey(key); err != nil {
+			t.Errorf("NewStorageKey(%q) = %q, which cleanKey rejects", tc.fileName, key)
+		}
+	}
+
+	if NewStorageKey("documents", "a.pdf") == NewStorageKey("documents", "a.pdf") {
+		t.Error("two uploads of the same name got the same key")
+	}
+}
+
+func TestKeyUnderURL(t *testing.T) {
+	const base = "https://cdn.example.com/files"
+
+	for _, tc := range []struct {
+		url  string
+		want string
+		ok   bool
+	}{
+		{base + "/documents/a.pdf", "documents/a.pdf", true},
+		{base + "/documents/my%20notes.pdf", "documents/my notes.pdf", true},
+		{base + "/documents/a.pdf?X-Amz-Expires=60", "documents/a.pdf", true},
+		{"https://other.example.com/files/documents/a.pdf", "", false},
+		{base + "documents/a.pdf", "", false},
+		{base + "/", "", false},
+		{base + "/documents/../../secret", "", false},
+		{base + "/documents/%2e%2e/%2e%2e/secret", "", false},
+		{base + "/documents/%zz.pdf", "", false},
+	} {
+		key, ok := keyUnderURL(base, tc.url)
+		if key != tc.want || ok != tc.ok {
+			t.Errorf("keyUnderURL(%q) = %q, %v, want %q, %v", tc.url, key, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	s := &LocalStorage{Dir: t.TempDir(), BaseURL: "http://localhost:8080/files"}
+
+	fileURL, err := s.Put(ctx, "documents/my notes.pdf", strings.NewReader("hello"), "application/pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileURL != "http://localhost:8080/files/documents/my%20notes.pdf" {
+		t.Fatalf("Put URL = %q", fileURL)
+	}
+	if key, ok := s.KeyForURL(fileURL); !ok || key != "documents/my notes.pdf" {
+		t.Fatalf("KeyForURL(%q) = %q, %v", fileURL, key, ok)
+	}
+
+	r, err := s.Get(ctx, "documents/my notes.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(r)
+	r.Close()
+	if string(body) != "hello" {
+		t.Fatalf("Get = %q, want %q", body, "hello")
+	}
+
+	if _, err := s.Put(ctx, "../escape.txt", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Fatal("Put accepted a key outside the storage directory")
+	}
+	if _, err := s.Get(ctx, "documents/../../escape.txt"); err == nil || errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("Get of an escaping key = %v, want an invalid key error", err)
+	}
+
+	s.Put(ctx, "documents/b.pdf", strings.NewReader("b"), "application/pdf")
+	s.Put(ctx, "blog_images/c.png", strings.NewReader("c"), "image/png")
+
+	var keys []string
+	err = s.List(ctx, "documents/", func(info FileInfo) error {
+		if info.ModTime.IsZero() {
+			t.Errorf("%s has no modification time", info.Key)
+		}
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "documents/b.pdf,documents/my notes.pdf" {
+		t.Fatalf("List(documents/) = %v", keys)
+	}
+	if err := s.List(ctx, "missing/", func(FileInfo) error { return nil }); err != nil {
+		t.Fatalf("List of a missing folder = %v", err)
+	}
+
+	if err := s.Delete(ctx, "documents/my notes.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "documents/my notes.pdf"); !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrFileNotFound", err)
+	}
+	if err := s.Delete(ctx, "documents/my notes.pdf"); err != nil {
+		t.Fatalf("deleting a missing file = %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var ErrUnsupportedFormat = errors.New("text extraction is not supported for this file type")
+
+const (
+	// MaxExtractedText is how many characters of text ExtractText returns at most
+	MaxExtractedText = 200000
+
+	// maxDocumentXML caps how much of a DOCX body is decompressed, against zip bombs
+	maxDocumentXML = 32 << 20
+)
+
+// textLimit counts the characters extracted so far against MaxExtractedText
+type textLimit int
+
+// take accounts for text and reports whether extraction should go on
+func (l *textLimit) take(text string) bool {
+	*l += textLimit(utf8.RuneCountInString(text))
+	return *l < MaxExtractedText
+}
+
+// ExtractText returns the text of a PDF, DOCX or plain text file, one entry per page.
+// DOCX pages are split on explicit and rendered page breaks; plain text is one page.
+func ExtractText(data []byte, fileName string) (pages []string, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("malformed file: %v", r)
+		}
+	}()
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".pdf":
+		return extractPDF(data)
+	case ".docx":
+		return extractDOCX(data)
+	case ".txt", ".md":
+		if !utf8.Valid(data) {
+			return nil, errors.New("text file is not valid UTF-8")
+		}
+		return []string{string(data)}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func extractPDF(data []byte) ([]string, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var extracted textLimit
+	pages := make([]string, 0, reader.NumPage())
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", i, err)
+		}
+		pages = append(pages, normalizeExtractedText(text))
+		if !extracted.take(text) {
+			break
+		}
+	}
+	return pages, nil
+}
+
+func extractDOCX(data []byte) ([]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.ReadCloser
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			if body, err = f.Open(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if body == nil {
+		return nil, errors.New("not a Word document")
+	}
+	defer body.Close()
+
+	// Stop at whatever comes first: the end of the body, the decompression cap
+	// or enough text
+	limited := &io.LimitedReader{R: body, N: maxDocumentXML}
+	var extracted textLimit
+	var pages []string
+	var page strings.Builder
+	decoder := xml.NewDecoder(limited)
+	for extracted < MaxExtractedText {
+		token, err := decoder.Token()
+		if err == io.EOF || (err != nil && limited.N <= 0) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					if limited.N <= 0 {
+						break
+					}
+					return nil, err
+				}
+				page.WriteString(text)
+				extracted.take(text)
+			case "tab":
+				page.WriteString("\t")
+			case "br":
+				if attr(t, "type") == "page" {
+					pages = append(pages, normalizeExtractedText(page.String()))
+					page.Reset()
+				} else {
+					page.WriteString("\n")
+				}
+			case "lastRenderedPageBreak":
+				if page.Len() > 0 {
+					pages = append(pages, normalizeExtractedText(page.String()))
+					page.Reset()
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == "p" {
+				page.WriteString("\n")
+			}
+		}
+	}
+	pages = append(pages, normalizeExtractedText(page.String()))
+
+	return pages, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// normalizeExtractedText trims lines and collapses runs of blank lines
+func normalizeExtractedText(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 seed from RFC 6238 appendix B
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPReferenceVectors(t *testing.T) {
+	// RFC 6238 lists 8 digit codes; 6 digit codes are their last six digits
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	} {
+		if got := totpCode([]byte("12345678901234567890"), tc.unix/totpPeriod); got != tc.code {
+			t.Errorf("code at %d = %s, want %s", tc.unix, got, tc.code)
+		}
+
+		now := time.Unix(tc.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, tc.code, 0, now)
+		if !ok || step != tc.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s) at %d = %d, %v, want step %d", tc.code, tc.unix, step, ok, tc.unix/totpPeriod)
+		}
+	}
+}
+
+func TestTOTPWindow(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	for _, tc := range []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	} {
+		code := totpCode(key, current+tc.offset)
+		step, ok := ValidateTOTP(rfc6238Secret, code, 0, now)
+		if ok != tc.ok {
+			t.Errorf("code from step %+d accepted = %v, want %v", tc.offset, ok, tc.ok)
+		}
+		if ok && step != current+tc.offset {
+			t.Errorf("code from step %+d matched step %d", tc.offset, step-current)
+		}
+	}
+
+	// codes are accepted as typed, in any case of secret
+	code := totpCode(key, current)
+	spaced := " " + code[:3] + " " + code[3:] + " "
+	if _, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), spaced, 0, now); !ok {
+		t.Errorf("ValidateTOTP(%q) with a lower case secret failed", spaced)
+	}
+
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, bad, 0, now); ok {
+			t.Errorf("ValidateTOTP(%q) succeeded", bad)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", code, 0, now); ok {
+		t.Error("ValidateTOTP with an invalid secret succeeded")
+	}
+}
+
+func TestTOTPReplay(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+	code := totpCode(key, current)
+
+	step, ok := ValidateTOTP(rfc6238Secret, code, 0, now)
+	if !ok {
+		t.Fatal("first use of the code failed")
+	}
+
+	// the step accepted last is stored as totpLastStep and passed back in
+	if _, ok := ValidateTOTP(rfc6238Secret, code, step, now); ok {
+		t.Fatal("the same code was accepted twice")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code, step, now.Add(totpPeriod*time.Second)); ok {
+		t.Fatal("the code was accepted again while still inside the window")
+	}
+
+	// a code from before the last accepted step is refused too
+	previous := totpCode(key, current-1)
+	if _, ok := ValidateTOTP(rfc6238Secret, previous, step, now); ok {
+		t.Fatal("an older code was accepted after a newer one")
+	}
+
+	next := totpCode(key, current+1)
+	if got, ok := ValidateTOTP(rfc6238Secret, next, step, now.Add(totpPeriod*time.Second)); !ok || got != current+1 {
+		t.Fatalf("next code = %d, %v, want step %d", got, ok, current+1)
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tr-choudhury21/prepportal_backend/config"
+	"github.com/tr-choudhury21/prepportal_backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+var (
+	userTokenCollection *mongo.Collection
+	userTokenOnce       sync.Once
+)
+
+func getUserTokenCollection() *mongo.Collection {
+	userTokenOnce.Do(func() {
+		userTokenCollection = config.GetCollection("user_tokens")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, _ = userTokenCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+			{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+		})
+	})
+	return userTokenCollection
+}
+
+// CreateUserToken issues a new token for the purpose, replacing any unused one,
+// and returns the raw value to be emailed. Only its hash is stored.
+func CreateUserToken(ctx context.Context, userID primitive.ObjectID, purpose, email string, ttl time.Duration) (string, error) {
+	tokens := getUserTokenCollection()
+
+	raw, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := RevokeUserTokens(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	_, err = tokens.InsertOne(ctx, models.UserToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: HashToken(raw),
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+// ConsumeUserToken atomically marks a valid token as used and returns it
+func ConsumeUserToken(ctx context.Context, raw, purpose string) (*models.UserToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"tokenHash": HashToken(raw),
+		"purpose":   purpose,
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": now}}
+
+	var token models.UserToken
+	err := getUserTokenCollection().FindOneAndUpdate(ctx, filter, update).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RevokeUserTokens removes every unused token of the purpose for the user
+func RevokeUserTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	_, err := getUserTokenCollection().DeleteMany(ctx, bson.M{
+		"userId":  userID,
+		"purpose": purpose,
+		"usedAt":  bson.M{"$exists": false},
+	})
+	return err
+}